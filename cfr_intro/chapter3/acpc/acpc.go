@@ -0,0 +1,298 @@
+// Package acpc plays Kuhn poker and Leduc Hold'em over the text protocol of the
+// Annual Computer Poker Competition, so that our strategies can meet third party bots.
+//
+// A dealer sends each agent the state of the hand from its point of view, for example
+//
+//	MATCHSTATE:0:12:cr/r:Qs|/Kh
+//
+// which is position 0 in hand 12, holding the queen of spades, facing a raise after the king of hearts is dealt.
+// As in the ACPC dealer, decks of n ranks are made of the n highest ranks.
+// When it is her turn, the agent replies with the same line followed by an action,
+// ":f" to fold, ":c" to check or call, and ":r" to bet or raise.
+// Lines end with "\r\n", and lines starting with '#' or ';' are comments.
+package acpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+const (
+	Version = "VERSION:2.0.0"
+
+	Fold  = 'f'
+	Call  = 'c'
+	Raise = 'r'
+
+	// roundSeparator separates betting rounds, and the board cards of each round.
+	roundSeparator = '/'
+	// cardSeparator separates the hole cards of each position.
+	cardSeparator = '|'
+
+	ranks = "23456789TJQKA"
+	suits = "shdc"
+)
+
+// NewGame returns the root of a two player ACPC game, "kuhn" or "leduc".
+func NewGame(name string) (tree.Game, error) {
+	switch name {
+	case "kuhn":
+		return games.Kuhn{State: kuhn.NewKuhn()}, nil
+	case "leduc":
+		return games.Leduc{State: leduc.NewLeduc()}, nil
+	}
+	return nil, errors.Errorf("unknown game %q", name)
+}
+
+// Supported returns an error if game cannot be played over the protocol.
+func Supported(game tree.Game) error {
+	switch g := game.(type) {
+	case games.Kuhn:
+		cfg := g.State.Config()
+		if cfg.NumPlayers != 2 {
+			return errors.Errorf("%d Kuhn players", cfg.NumPlayers)
+		}
+		if cfg.NumCards > len(ranks) {
+			return errors.Errorf("%d Kuhn cards", cfg.NumCards)
+		}
+		return nil
+	case games.Leduc:
+		return nil
+	}
+	return errors.Errorf("unsupported game %T", game)
+}
+
+// ActionChars returns the protocol character of each action of game.
+func ActionChars(game tree.Game) []byte {
+	chars := make([]byte, 0, game.ActionsLen())
+	switch g := game.(type) {
+	case games.Kuhn:
+		facing := g.State.FacingBet()
+		for a := 0; a < g.ActionsLen(); a++ {
+			switch {
+			case a == kuhn.Pass && facing:
+				chars = append(chars, Fold)
+			case a == kuhn.Pass:
+				chars = append(chars, Call)
+			case a == kuhn.Bet && facing:
+				chars = append(chars, Call)
+			default:
+				chars = append(chars, Raise)
+			}
+		}
+	case games.Leduc:
+		actions := make([]uint8, g.ActionsLen())
+		g.Actions(actions)
+		for _, a := range actions {
+			chars = append(chars, "fcr"[a])
+		}
+	}
+	return chars
+}
+
+// holeCard returns the hole card of position, or "" if it is not dealt yet.
+func holeCard(game tree.Game, position int) string {
+	switch g := game.(type) {
+	case games.Kuhn:
+		c := g.State.Card(position)
+		if c < 1 {
+			return ""
+		}
+		return cardString(c-1, g.State.Config().NumCards, 0)
+	case games.Leduc:
+		return leducCard(g.State.PrivateCard(position))
+	}
+	return ""
+}
+
+// boardCards returns the board cards of each round after the first.
+func boardCards(game tree.Game) []string {
+	if g, ok := game.(games.Leduc); ok {
+		if c := g.State.PublicCard(); c >= 0 {
+			return []string{leducCard(c)}
+		}
+	}
+	return nil
+}
+
+// cardString returns a card of a deck of the numRanks highest ranks, as the ACPC dealer deals.
+// The three ranks of Kuhn and Leduc are thus the queen, king and ace.
+func cardString(rank, numRanks, suit int) string {
+	return string(ranks[len(ranks)-numRanks+rank]) + string(suits[suit])
+}
+
+func leducCard(c int) string {
+	if c < 0 {
+		return ""
+	}
+	return cardString(c/2, 3, c%2)
+}
+
+// Cards returns the cards of game as seen by viewer, with the hole cards of the other positions hidden.
+// A negative viewer sees all cards.
+func Cards(game tree.Game, viewer int) string {
+	var b strings.Builder
+	for p := 0; p < game.NumPlayers(); p++ {
+		if p > 0 {
+			b.WriteByte(cardSeparator)
+		}
+		if viewer < 0 || viewer == p {
+			b.WriteString(holeCard(game, p))
+		}
+	}
+	for _, c := range boardCards(game) {
+		b.WriteByte(roundSeparator)
+		b.WriteString(c)
+	}
+	return b.String()
+}
+
+// MatchState is a state of a hand from the point of view of a position.
+type MatchState struct {
+	Position int
+	Hand     int
+	Betting  string
+	Cards    string
+}
+
+func (ms MatchState) String() string {
+	return fmt.Sprintf("MATCHSTATE:%d:%d:%s:%s", ms.Position, ms.Hand, ms.Betting, ms.Cards)
+}
+
+func ParseMatchState(line string) (MatchState, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 5 || fields[0] != "MATCHSTATE" {
+		return MatchState{}, errors.Errorf("invalid match state %q", line)
+	}
+	ms := MatchState{Betting: fields[3], Cards: fields[4]}
+	var err error
+	ms.Position, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return MatchState{}, errors.Wrap(err, "position")
+	}
+	ms.Hand, err = strconv.Atoi(fields[2])
+	if err != nil {
+		return MatchState{}, errors.Wrap(err, "hand")
+	}
+	return ms, nil
+}
+
+// ParseResponse splits the response of an agent into its match state and action.
+func ParseResponse(line string) (string, byte, error) {
+	i := strings.LastIndexByte(line, ':')
+	if i < 0 || i != len(line)-2 {
+		return "", 0, errors.Errorf("invalid response %q", line)
+	}
+	return line[:i], line[i+1], nil
+}
+
+// hideCards hides the hole cards of the positions other than viewer, which are shown at showdown.
+func hideCards(cards string, viewer int) string {
+	rounds := strings.Split(cards, string(roundSeparator))
+	holes := strings.Split(rounds[0], string(cardSeparator))
+	for p := range holes {
+		if p != viewer {
+			holes[p] = ""
+		}
+	}
+	rounds[0] = strings.Join(holes, string(cardSeparator))
+	return strings.Join(rounds, string(roundSeparator))
+}
+
+// Reconstruct returns a state of root consistent with ms, in which the cards hidden from ms.Position are dealt arbitrarily.
+// The state is as good as the real one for the agent at ms.Position, since it has the same infoset.
+func Reconstruct(root tree.Game, ms MatchState) (tree.Game, error) {
+	cards := hideCards(ms.Cards, ms.Position)
+	betting := strings.Replace(ms.Betting, string(roundSeparator), "", -1)
+	game, _, ok := reconstruct(root, ms.Position, cards, betting, nil)
+	if !ok {
+		return nil, errors.Errorf("no state of %T matches %s", root, ms)
+	}
+	return game, nil
+}
+
+// ParseState parses a hand logged by the dealer, such as "STATE:0:cc:Ks|Qs:1|-1:a|b", into a hand of root.
+func ParseState(root tree.Game, line string) (match.Hand, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 6 || fields[0] != "STATE" {
+		return match.Hand{}, errors.Errorf("invalid state %q", line)
+	}
+	betting := strings.Replace(fields[2], string(roundSeparator), "", -1)
+	_, steps, ok := reconstruct(root, -1, fields[3], betting, nil)
+	if !ok {
+		return match.Hand{}, errors.Errorf("no hand of %T matches %q", root, line)
+	}
+	h := match.Hand{Players: strings.Split(fields[5], "|"), Steps: steps}
+	for _, s := range strings.Split(fields[4], "|") {
+		p, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return match.Hand{}, errors.Wrap(err, "payoff")
+		}
+		h.Payoffs = append(h.Payoffs, p)
+	}
+	return h, nil
+}
+
+// ReadHands reads the hands of a log of the dealer, or of match -log, as hands of root.
+// Lines other than STATE and HAND lines are skipped.
+func ReadHands(r io.Reader, root tree.Game) ([]match.Hand, error) {
+	hands := make([]match.Hand, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var h match.Hand
+		var err error
+		switch {
+		case strings.HasPrefix(line, "HAND:"):
+			h, err = match.ParseHand(line)
+		case strings.HasPrefix(line, "STATE:"):
+			h, err = ParseState(root, line)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "hand %d", len(hands))
+		}
+		hands = append(hands, h)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Scan")
+	}
+	return hands, nil
+}
+
+// reconstruct searches the states after game whose cards seen by viewer and betting match,
+// and returns the first one found together with the steps to it.
+func reconstruct(game tree.Game, viewer int, cards, betting string, steps []int) (tree.Game, []int, bool) {
+	if game.IsChanceNode() {
+		for o := 0; o < game.ChanceLen(); o++ {
+			if g, s, ok := reconstruct(game.Chance(o), viewer, cards, betting, append(steps, o)); ok {
+				return g, s, true
+			}
+		}
+		return nil, nil, false
+	}
+	seen := Cards(game, viewer)
+	if betting == "" {
+		return game, steps, seen == cards
+	}
+	if !strings.HasPrefix(cards, seen) || game.IsTerminal() {
+		return nil, nil, false
+	}
+	for aIdx, c := range ActionChars(game) {
+		if c == betting[0] {
+			return reconstruct(game.Play(aIdx), viewer, cards, betting[1:], append(steps, aIdx))
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,53 @@
+package acpc
+
+import (
+	"io"
+	"net"
+
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Dial connects to the dealer at addr and announces the protocol version.
+func Dial(addr string) (*Conn, error) {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Dial")
+	}
+	conn := NewConn(nc)
+	if err := conn.WriteLine(Version); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "WriteLine")
+	}
+	return conn, nil
+}
+
+// Play plays the hands sent by the dealer until it closes the connection.
+// act returns the index of the action to take at game, which is a state reconstructed from the match state.
+func Play(conn *Conn, root tree.Game, act func(game tree.Game) int) error {
+	for {
+		line, err := conn.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "ReadLine")
+		}
+		ms, err := ParseMatchState(line)
+		if err != nil {
+			return errors.Wrap(err, "ParseMatchState")
+		}
+		game, err := Reconstruct(root, ms)
+		if err != nil {
+			return errors.Wrap(err, "Reconstruct")
+		}
+		if game.IsTerminal() || game.CurPlayer() != ms.Position {
+			continue
+		}
+
+		c := ActionChars(game)[act(game)]
+		if err := conn.WriteLine(line + ":" + string(c)); err != nil {
+			return errors.Wrap(err, "WriteLine")
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package acpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// Conn sends and receives the lines of the protocol.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *Conn) WriteLine(line string) error {
+	if _, err := io.WriteString(c.conn, line+"\r\n"); err != nil {
+		return errors.Wrap(err, "WriteString")
+	}
+	return nil
+}
+
+// ReadLine returns the next line that is not a comment, without the line ending.
+func (c *Conn) ReadLine() (string, error) {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return "", io.EOF
+			}
+			return "", errors.Wrap(err, "ReadString")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		return line, nil
+	}
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Accept accepts an agent on l and checks its protocol version.
+func Accept(l net.Listener) (*Conn, error) {
+	nc, err := l.Accept()
+	if err != nil {
+		return nil, errors.Wrap(err, "Accept")
+	}
+	conn := NewConn(nc)
+	version, err := conn.ReadLine()
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "ReadLine")
+	}
+	if !strings.HasPrefix(version, "VERSION:2.") {
+		conn.Close()
+		return nil, errors.Errorf("unsupported version %q", version)
+	}
+	return conn, nil
+}
+
+// Dealer runs a match of two player hands between the agents in its seats.
+// Agents swap positions every hand, so that the agent in seat s plays position (s+hand)%2.
+type Dealer struct {
+	root  tree.Game
+	names []string
+	seats []*Conn
+	// log receives the hands of the match in the format of ACPC logs.
+	log io.Writer
+	// chance deals the cards.
+	chance chance.Source
+
+	// Scores are the total payoffs of each seat.
+	Scores []float64
+	// Hands is the number of hands played.
+	Hands int
+}
+
+// NewDealer returns a dealer of the game of root, whose cards are dealt by src.
+func NewDealer(root tree.Game, names []string, seats []*Conn, log io.Writer, src chance.Source) (*Dealer, error) {
+	if err := Supported(root); err != nil {
+		return nil, errors.Wrap(err, "Supported")
+	}
+	if len(names) != root.NumPlayers() || len(seats) != root.NumPlayers() {
+		return nil, errors.Errorf("%d names and %d seats for %d players", len(names), len(seats), root.NumPlayers())
+	}
+	d := &Dealer{
+		root:   root,
+		names:  names,
+		seats:  seats,
+		log:    log,
+		chance: src,
+		Scores: make([]float64, len(seats)),
+	}
+	return d, nil
+}
+
+func (d *Dealer) seat(hand, position int) int {
+	n := len(d.seats)
+	return ((position-hand)%n + n) % n
+}
+
+// send sends the state of the hand to every position.
+// Hole cards are shown to everyone at a showdown.
+func (d *Dealer) send(game tree.Game, hand int, betting string, showdown bool) error {
+	for position := range d.seats {
+		viewer := position
+		if showdown {
+			viewer = -1
+		}
+		ms := MatchState{Position: position, Hand: hand, Betting: betting, Cards: Cards(game, viewer)}
+		if err := d.seats[d.seat(hand, position)].WriteLine(ms.String()); err != nil {
+			return errors.Wrapf(err, "position %d", position)
+		}
+	}
+	return nil
+}
+
+// action reads the action of position and returns its index in game.
+// Invalid actions are taken as a call, as in the ACPC dealer.
+func (d *Dealer) action(game tree.Game, hand int, betting string) (int, byte, error) {
+	position := game.CurPlayer()
+	ms := MatchState{Position: position, Hand: hand, Betting: betting, Cards: Cards(game, position)}
+	line, err := d.seats[d.seat(hand, position)].ReadLine()
+	if err != nil {
+		return -1, 0, errors.Wrapf(err, "position %d", position)
+	}
+	state, c, err := ParseResponse(line)
+	if err != nil {
+		return -1, 0, errors.Wrap(err, "ParseResponse")
+	}
+	if state != ms.String() {
+		return -1, 0, errors.Errorf("response %q to %q", line, ms)
+	}
+
+	chars := ActionChars(game)
+	for aIdx, ac := range chars {
+		if ac == c {
+			return aIdx, c, nil
+		}
+	}
+	glog.Warningf("invalid action %q of %s, taken as a call", line, d.names[d.seat(hand, position)])
+	for aIdx, ac := range chars {
+		if ac == Call {
+			return aIdx, Call, nil
+		}
+	}
+	return -1, 0, errors.Errorf("no call in %q", chars)
+}
+
+// fmtChips formats chips without the sign of a negative zero, which a split pot gives.
+func fmtChips(c float64) string {
+	if c == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%g", c)
+}
+
+// PlayHand plays a hand and returns the payoffs of each seat.
+func (d *Dealer) PlayHand(hand int) ([]float64, error) {
+	game := d.root
+	var betting []byte
+	for !game.IsTerminal() {
+		if game.IsChanceNode() {
+			o, err := d.chance.Outcome(game)
+			if err != nil {
+				return nil, errors.Wrapf(err, "hand %d", hand)
+			}
+			game = game.Chance(o)
+			if len(betting) > 0 {
+				betting = append(betting, roundSeparator)
+			}
+			continue
+		}
+
+		if err := d.send(game, hand, string(betting), false); err != nil {
+			return nil, err
+		}
+		aIdx, c, err := d.action(game, hand, string(betting))
+		if err != nil {
+			return nil, err
+		}
+		game = game.Play(aIdx)
+		betting = append(betting, c)
+	}
+	showdown := betting[len(betting)-1] != Fold
+	if err := d.send(game, hand, string(betting), showdown); err != nil {
+		return nil, err
+	}
+
+	payoff := make([]float64, game.NumPlayers())
+	game.Payoff(payoff)
+	seatPayoff := make([]float64, len(payoff))
+	payoffs := make([]string, 0, len(payoff))
+	names := make([]string, 0, len(payoff))
+	for position, p := range payoff {
+		seat := d.seat(hand, position)
+		seatPayoff[seat] = p
+		d.Scores[seat] += p
+		payoffs = append(payoffs, fmtChips(p))
+		names = append(names, d.names[seat])
+	}
+	if d.log != nil {
+		_, err := fmt.Fprintf(d.log, "STATE:%d:%s:%s:%s:%s\n", hand, betting, Cards(game, -1), strings.Join(payoffs, "|"), strings.Join(names, "|"))
+		if err != nil {
+			return nil, errors.Wrap(err, "Fprintf")
+		}
+	}
+	return seatPayoff, nil
+}
+
+// Run plays hands hands, or fewer if the cards run out with chance.ErrEnd, and logs the final score of each agent.
+func (d *Dealer) Run(hands int) error {
+	for d.Hands < hands {
+		_, err := d.PlayHand(d.Hands)
+		if errors.Cause(err) == chance.ErrEnd {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "hand %d", d.Hands)
+		}
+		d.Hands++
+	}
+	if d.log != nil {
+		scores := make([]string, 0, len(d.Scores))
+		for _, s := range d.Scores {
+			scores = append(scores, fmtChips(s))
+		}
+		_, err := fmt.Fprintf(d.log, "SCORE:%s:%s\n", strings.Join(scores, "|"), strings.Join(d.names, "|"))
+		if err != nil {
+			return errors.Wrap(err, "Fprintf")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package aivat estimates the payoff of an agent from the hands it played, with the variance reduction of
+// "AIVAT: A New Variance Reduction Technique for Agent Evaluation in Imperfect Information Games" by Burch et al.
+//
+// Every chance outcome, and every action of the evaluated agent whose policy is known,
+// is scored by how much it changes a value function, compared with the expected change under the known probabilities.
+// These corrections have zero mean whatever the value function, so subtracting them from the payoff keeps the estimate unbiased,
+// and the better the value function predicts the payoff, the lower the variance.
+// The value function here is the expected payoff when every player plays a trained strategy.
+package aivat
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+type Evaluator struct {
+	root tree.Game
+	// policy is the known policy of the evaluated agent.
+	policy strategy.Policy
+	// value is the profile whose expected payoffs are the value function.
+	value strategy.Policy
+
+	// rootValues caches the values of the children of the root, which are needed by every hand.
+	rootValues map[int][]float64
+}
+
+func NewEvaluator(root tree.Game, policy, value strategy.Policy) *Evaluator {
+	e := &Evaluator{
+		root:       root,
+		policy:     policy,
+		value:      value,
+		rootValues: make(map[int][]float64),
+	}
+	return e
+}
+
+// childValues returns the values of the children of game, where step is the number of steps from the root to game.
+func (e *Evaluator) childValues(game tree.Game, step int) [][]float64 {
+	n := game.ActionsLen()
+	if game.IsChanceNode() {
+		n = game.ChanceLen()
+	}
+	values := make([][]float64, n)
+	for i := range values {
+		if step == 0 {
+			if v, ok := e.rootValues[i]; ok {
+				values[i] = v
+				continue
+			}
+		}
+
+		var child tree.Game
+		if game.IsChanceNode() {
+			child = game.Chance(i)
+		} else {
+			child = game.Play(i)
+		}
+		values[i] = match.Value(child, e.value)
+		if step == 0 {
+			e.rootValues[i] = values[i]
+		}
+	}
+	return values
+}
+
+// Estimate returns the payoff of the player at position in hand, and its AIVAT estimate.
+func (e *Evaluator) Estimate(hand match.Hand, position int) (float64, float64, error) {
+	states, err := hand.Replay(e.root)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Replay")
+	}
+	end := states[len(states)-1]
+	payoff := make([]float64, end.NumPlayers())
+	end.Payoff(payoff)
+	if len(hand.Payoffs) != len(payoff) || hand.Payoffs[position] != payoff[position] {
+		return 0, 0, errors.Errorf("logged payoffs %v instead of %v", hand.Payoffs, payoff)
+	}
+
+	var correction float64
+	for i, s := range hand.Steps {
+		game := states[i]
+		var probs []float64
+		switch {
+		case game.IsChanceNode():
+			probs = make([]float64, game.ChanceLen())
+			for o := range probs {
+				probs[o] = game.ChanceProb(o)
+			}
+		case game.CurPlayer() == position:
+			probs = e.policy.Probs(game)
+		default:
+			// The policy of the opponent is unknown, so her actions are not corrected.
+			continue
+		}
+
+		values := e.childValues(game, i)
+		var expected float64
+		for j, p := range probs {
+			expected += p * values[j][position]
+		}
+		correction += values[s][position] - expected
+	}
+	return payoff[position], payoff[position] - correction, nil
+}
+
+// Evaluate returns the mean payoff of player over the hands she played, and its AIVAT estimate.
+func (e *Evaluator) Evaluate(hands []match.Hand, player string) (match.Result, match.Result, error) {
+	var naive, corrected match.Stats
+	for i, h := range hands {
+		position := h.Position(player)
+		if position < 0 {
+			continue
+		}
+		payoff, estimate, err := e.Estimate(h, position)
+		if err != nil {
+			return match.Result{}, match.Result{}, errors.Wrapf(err, "hand %d", i)
+		}
+		naive.Add(payoff)
+		corrected.Add(estimate)
+	}
+	return naive.Result(), corrected.Result(), nil
+}
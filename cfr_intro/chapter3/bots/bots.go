@@ -0,0 +1,187 @@
+// Package bots implements heuristic policies, which put the strength of trained strategies in context.
+//
+// Bots without a notion of their game, such as Call in a matrix game, play uniformly at random.
+package bots
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Names are the names of the bots accepted by New.
+var Names = []string{"random", "call", "raise", "dudo"}
+
+// New returns the bot named name, where the threshold of the Dudo bot may follow a colon, such as "dudo:0.7".
+func New(name string) (strategy.Policy, error) {
+	switch name {
+	case "random":
+		return Random{}, nil
+	case "call":
+		return Call{}, nil
+	case "raise":
+		return Raise{}, nil
+	case "dudo":
+		return Dudo{Threshold: DefaultThreshold}, nil
+	}
+	if strings.HasPrefix(name, "dudo:") {
+		threshold, err := strconv.ParseFloat(strings.TrimPrefix(name, "dudo:"), 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "ParseFloat")
+		}
+		return Dudo{Threshold: threshold}, nil
+	}
+	return nil, errors.Errorf("unknown bot %q", name)
+}
+
+// IsBot reports whether name names a bot.
+func IsBot(name string) bool {
+	_, err := New(name)
+	return err == nil
+}
+
+func uniform(n int) []float64 {
+	probs := make([]float64, n)
+	for i := range probs {
+		probs[i] = 1 / float64(n)
+	}
+	return probs
+}
+
+// pure returns the strategy that always takes action aIdx out of n actions.
+func pure(n, aIdx int) []float64 {
+	probs := make([]float64, n)
+	probs[aIdx] = 1
+	return probs
+}
+
+// Random plays uniformly at random.
+type Random struct{}
+
+func (Random) Probs(game tree.Game) []float64 {
+	return uniform(game.ActionsLen())
+}
+
+// Call always checks or calls in poker, and calls dudo on every claim in Dudo.
+// It makes the weakest claim when there is no claim to call.
+type Call struct{}
+
+func (Call) Probs(game tree.Game) []float64 {
+	n := game.ActionsLen()
+	switch g := game.(type) {
+	case games.Kuhn:
+		if g.State.FacingBet() {
+			return pure(n, kuhn.Bet)
+		}
+		return pure(n, kuhn.Pass)
+	case games.Leduc:
+		return pure(n, leducIndex(g, leduc.Call))
+	case games.Dudo:
+		if len(g.State.History()) == 0 {
+			return pure(n, 0)
+		}
+		return pure(n, dudoIndex(g, g.State.DudoAction()))
+	}
+	return uniform(n)
+}
+
+// Raise always bets or raises when it can, and otherwise calls.
+// In Dudo, it always makes the weakest claim it can, and calls dudo when it cannot.
+type Raise struct{}
+
+func (Raise) Probs(game tree.Game) []float64 {
+	n := game.ActionsLen()
+	switch g := game.(type) {
+	case games.Kuhn:
+		if !g.State.FacingBet() {
+			return pure(n, kuhn.Bet)
+		}
+		if n > kuhn.Raise {
+			return pure(n, kuhn.Raise)
+		}
+		return pure(n, kuhn.Bet)
+	case games.Leduc:
+		if aIdx := leducIndex(g, leduc.Raise); aIdx >= 0 {
+			return pure(n, aIdx)
+		}
+		return pure(n, leducIndex(g, leduc.Call))
+	case games.Dudo:
+		// Claims come first in the actions, so the first action is the weakest claim if there is any.
+		return pure(n, 0)
+	}
+	return uniform(n)
+}
+
+// leducIndex returns the index of action a at game, or -1 if a is not allowed.
+func leducIndex(game games.Leduc, a uint8) int {
+	actions := make([]uint8, game.ActionsLen())
+	game.Actions(actions)
+	for i, b := range actions {
+		if a == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// dudoIndex returns the index of action a at game, or -1 if a is not allowed.
+func dudoIndex(game games.Dudo, a uint16) int {
+	actions := make([]uint16, game.ActionsLen())
+	game.Actions(actions)
+	for i, b := range actions {
+		if a == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// DefaultThreshold calls dudo whenever the last claim is more likely false than true.
+const DefaultThreshold = 0.5
+
+// Dudo plays Dudo by the likelihood of claims given its own dices, which is computed by dudo.Dudo.ClaimProb.
+// It calls dudo when the last claim is false with a probability above Threshold,
+// and otherwise makes the allowed claim most likely to be true, preferring weaker claims among equally likely ones.
+type Dudo struct {
+	Threshold float64
+}
+
+func (bot Dudo) Probs(game tree.Game) []float64 {
+	n := game.ActionsLen()
+	g, ok := game.(games.Dudo)
+	if !ok {
+		return uniform(n)
+	}
+	player := g.CurPlayer()
+	claims := g.State.Claims()
+	history := g.State.History()
+	if len(history) > 0 {
+		last := claims[history[len(history)-1]]
+		if 1-g.State.ClaimProb(player, last, false) > bot.Threshold {
+			return pure(n, dudoIndex(g, g.State.DudoAction()))
+		}
+	}
+
+	actions := make([]uint16, n)
+	g.Actions(actions)
+	best, bestProb := -1, -1.0
+	for i, a := range actions {
+		if a >= g.State.DudoAction() {
+			break
+		}
+		if p := g.State.ClaimProb(player, claims[a], false); p > bestProb {
+			best, bestProb = i, p
+		}
+	}
+	if best < 0 {
+		// No claim is stronger than the last one.
+		return pure(n, dudoIndex(g, g.State.DudoAction()))
+	}
+	return pure(n, best)
+}
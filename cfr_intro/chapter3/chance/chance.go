@@ -0,0 +1,170 @@
+// Package chance abstracts where the outcomes of chance nodes come from,
+// so that a run can be driven by a random number generator, replayed from a recorded trace, or scripted by hand.
+//
+// Recording the chance stream of a run that hits a bug, and replaying the trace, reproduces the run exactly.
+// Traces are text files with one outcome per line, such as the index of a deal or a roll.
+// Games may format outcomes in their own way, such as the card orders of a Kuhn deal read by KuhnTrainer.java,
+// so that the same deals can be fed to other implementations.
+package chance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrEnd is the cause of the error of a Trace that has run out.
+var ErrEnd = errors.New("end of trace")
+
+// Node is a chance node, such as a tree.Game, a kuhn.Kuhn or a dudo.Dudo.
+type Node interface {
+	ChanceLen() int
+	ChanceProb(outcome int) float64
+}
+
+// Source provides the outcomes of chance nodes.
+type Source interface {
+	// Outcome returns the outcome of the chance node in [0, node.ChanceLen()).
+	Outcome(node Node) (int, error)
+}
+
+// Live samples outcomes by their probabilities with a random number generator.
+type Live struct {
+	rand *rand.Rand
+}
+
+// NewLive returns a source seeded by seed.
+func NewLive(seed int64) *Live {
+	return &Live{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (l *Live) Outcome(node Node) (int, error) {
+	return l.Sample(node), nil
+}
+
+// Sample returns an outcome of node, for callers that cannot handle the errors of other sources.
+func (l *Live) Sample(node Node) int {
+	r := l.rand.Float64()
+	var cumulative float64 = 0
+	numOutcomes := node.ChanceLen()
+	for o := 0; o < numOutcomes-1; o++ {
+		cumulative += node.ChanceProb(o)
+		if r < cumulative {
+			return o
+		}
+	}
+	return numOutcomes - 1
+}
+
+func check(node Node, outcome int) error {
+	if outcome < 0 || outcome >= node.ChanceLen() {
+		return errors.Errorf("outcome %d not in [0, %d)", outcome, node.ChanceLen())
+	}
+	return nil
+}
+
+// Script plays a fixed list of outcomes over and over.
+type Script struct {
+	outcomes []int
+	next     int
+}
+
+func NewScript(outcomes []int) (*Script, error) {
+	if len(outcomes) == 0 {
+		return nil, errors.Errorf("empty script")
+	}
+	return &Script{outcomes: outcomes}, nil
+}
+
+// ParseScript parses a comma separated list of outcomes, such as "4,0,1".
+func ParseScript(s string) (*Script, error) {
+	outcomes := make([]int, 0)
+	for _, f := range strings.Split(s, ",") {
+		o, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, errors.Wrap(err, "Atoi")
+		}
+		outcomes = append(outcomes, o)
+	}
+	return NewScript(outcomes)
+}
+
+func (s *Script) Outcome(node Node) (int, error) {
+	o := s.outcomes[s.next]
+	if err := check(node, o); err != nil {
+		return -1, errors.Wrapf(err, "script step %d", s.next)
+	}
+	s.next = (s.next + 1) % len(s.outcomes)
+	return o, nil
+}
+
+// Trace replays the outcomes of a trace, and fails with ErrEnd once the trace runs out.
+// Empty lines and lines starting with '#' are skipped.
+type Trace struct {
+	scanner *bufio.Scanner
+	parse   func(line string) (int, error)
+	line    int
+}
+
+// NewTrace returns a source reading the trace r, in which each line is parsed by parse,
+// or is the outcome itself if parse is nil.
+func NewTrace(r io.Reader, parse func(line string) (int, error)) *Trace {
+	if parse == nil {
+		parse = strconv.Atoi
+	}
+	return &Trace{scanner: bufio.NewScanner(r), parse: parse}
+}
+
+func (t *Trace) Outcome(node Node) (int, error) {
+	for t.scanner.Scan() {
+		t.line++
+		line := strings.TrimSpace(t.scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		o, err := t.parse(line)
+		if err != nil {
+			return -1, errors.Wrapf(err, "line %d", t.line)
+		}
+		if err := check(node, o); err != nil {
+			return -1, errors.Wrapf(err, "line %d", t.line)
+		}
+		return o, nil
+	}
+	if err := t.scanner.Err(); err != nil {
+		return -1, errors.Wrap(err, "Scan")
+	}
+	return -1, errors.Wrapf(ErrEnd, "after %d lines", t.line)
+}
+
+// Recorder writes the outcomes of its source to a trace.
+type Recorder struct {
+	src    Source
+	w      io.Writer
+	format func(outcome int) string
+}
+
+// NewRecorder returns a source recording the outcomes of src to w, formatted by format,
+// or as the outcomes themselves if format is nil.
+func NewRecorder(src Source, w io.Writer, format func(outcome int) string) *Recorder {
+	if format == nil {
+		format = strconv.Itoa
+	}
+	return &Recorder{src: src, w: w, format: format}
+}
+
+func (r *Recorder) Outcome(node Node) (int, error) {
+	o, err := r.src.Outcome(node)
+	if err != nil {
+		return -1, err
+	}
+	if _, err := fmt.Fprintln(r.w, r.format(o)); err != nil {
+		return -1, errors.Wrap(err, "Fprintln")
+	}
+	return o, nil
+}
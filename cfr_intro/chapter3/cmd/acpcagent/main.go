@@ -0,0 +1,45 @@
+// Command acpcagent plays a Kuhn or Leduc strategy saved by treecfr at an ACPC dealer.
+package main
+
+import (
+	"flag"
+
+	"github.com/fumin/bangbang/cfr/chapter3/acpc"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+)
+
+var (
+	strategyPath = flag.String("strategy", "", "path of the Kuhn or Leduc strategy saved by treecfr")
+	addr         = flag.String("addr", "localhost:18791", "address of the dealer")
+)
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	file, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	root, err := file.NewGame()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if err := acpc.Supported(root); err != nil {
+		glog.Fatalf("%+v", err)
+	}
+
+	conn, err := acpc.Dial(*addr)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	defer conn.Close()
+	act := func(game tree.Game) int {
+		return strategy.SampleAction(file.Probs(game))
+	}
+	if err := acpc.Play(conn, root, act); err != nil {
+		glog.Fatalf("%+v", err)
+	}
+}
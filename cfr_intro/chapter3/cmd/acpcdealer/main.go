@@ -0,0 +1,114 @@
+// Command acpcdealer runs a Kuhn or Leduc match between two agents speaking the ACPC protocol,
+// and writes the hands to a log in the ACPC format.
+//
+//	acpcdealer -game kuhn -hands 1000 -names cfr,bot -log match.log &
+//	acpcagent -strategy kuhn.gob -addr localhost:18791 &
+//	acpcagent -strategy kuhn.gob -addr localhost:18792
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/acpc"
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	gameName  = flag.String("game", "kuhn", "game to deal, kuhn or leduc")
+	hands     = flag.Int("hands", 1000, "number of hands")
+	ports     = flag.String("ports", "18791,18792", "comma separated localhost ports of each seat")
+	names     = flag.String("names", "p1,p2", "comma separated names of the agents in each seat")
+	logPath   = flag.String("log", "match.log", "path of the match log")
+	seed      = flag.Int64("seed", 0, "seed of the cards, random if zero")
+	tracePath = flag.String("chance", "", "path of a trace of deals to replay, such as recorded by match -record_chance")
+)
+
+// accept listens on all ports before accepting on any, so that agents can connect in any order.
+func accept(ports []string) ([]*acpc.Conn, error) {
+	listeners := make([]net.Listener, 0, len(ports))
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	for _, port := range ports {
+		l, err := net.Listen("tcp", "localhost:"+port)
+		if err != nil {
+			return nil, errors.Wrap(err, "Listen")
+		}
+		listeners = append(listeners, l)
+	}
+
+	seats := make([]*acpc.Conn, 0, len(ports))
+	for _, l := range listeners {
+		glog.Infof("waiting for seat %d on %s", len(seats), l.Addr())
+		conn, err := acpc.Accept(l)
+		if err != nil {
+			for _, s := range seats {
+				s.Close()
+			}
+			return nil, errors.Wrapf(err, "seat %d", len(seats))
+		}
+		seats = append(seats, conn)
+	}
+	return seats, nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	root, err := acpc.NewGame(*gameName)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	var src chance.Source = chance.NewLive(*seed)
+	if *tracePath != "" {
+		f, err := os.Open(*tracePath)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		defer f.Close()
+		src = chance.NewTrace(f, nil)
+	}
+	seats, err := accept(strings.Split(*ports, ","))
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	defer func() {
+		for _, s := range seats {
+			s.Close()
+		}
+	}()
+
+	log, err := os.Create(*logPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	defer log.Close()
+	fmt.Fprintf(log, "# %s match of %d hands between %s\n", *gameName, *hands, *names)
+
+	dealer, err := acpc.NewDealer(root, strings.Split(*names, ","), seats, log, src)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if err := dealer.Run(*hands); err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if dealer.Hands < *hands {
+		glog.Infof("cards ran out after %d hands", dealer.Hands)
+	}
+	for s, name := range strings.Split(*names, ",") {
+		glog.Infof("%s: %g, %.4f per hand", name, dealer.Scores[s], dealer.Scores[s]/float64(dealer.Hands))
+	}
+}
@@ -0,0 +1,64 @@
+// Command aivat estimates the payoff of an agent from a match log with AIVAT,
+// and compares it with the plain mean payoff.
+//
+// Logs are either written by match -log, or by acpcdealer:
+//
+//	aivat -log match.log -player a -strategy kuhn.gob
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/fumin/bangbang/cfr/chapter3/acpc"
+	"github.com/fumin/bangbang/cfr/chapter3/aivat"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/golang/glog"
+)
+
+var (
+	logPath      = flag.String("log", "", "path of the match log")
+	player       = flag.String("player", "a", "name of the evaluated agent in the log")
+	strategyPath = flag.String("strategy", "", "path of the strategy played by the evaluated agent")
+	valuePath    = flag.String("value", "", "path of the strategy whose self play values are the value function, the evaluated strategy if empty")
+)
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	policy, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	value := policy
+	if *valuePath != "" {
+		value, err = strategy.Load(*valuePath)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		if !value.SameGame(policy) {
+			glog.Fatalf("%s is of a different game than %s", *valuePath, *strategyPath)
+		}
+	}
+	root, err := policy.NewGame()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+
+	f, err := os.Open(*logPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	defer f.Close()
+	hands, err := acpc.ReadHands(f, root)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	naive, estimate, err := aivat.NewEvaluator(root, policy, value).Evaluate(hands, *player)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	glog.Infof("payoff of %s: %s", *player, naive)
+	glog.Infof("AIVAT estimate: %s", estimate)
+}
@@ -0,0 +1,294 @@
+// Command distill trains a policy network to play the average strategy of a strategy file,
+// and reports the exploitability of the network against that of the strategy.
+//
+//	distill -strategy dudo.gob -holdout 0.2 -steps 20000 -save /tmp/dudo_net
+//
+// The network is trained on the infosets not held out, and its loss on the held out ones
+// measures how well it generalizes to infosets never seen in training.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/distill"
+	"github.com/fumin/bangbang/cfr/chapter3/exploit"
+	"github.com/fumin/bangbang/cfr/chapter3/features"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	awawtf "github.com/fumin/bangbang/util/tensorflow"
+	tfpb "github.com/fumin/bangbang/util/tensorflow/protos_all_go_proto"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+var (
+	strategyPath = flag.String("strategy", "", "path of the strategy to distill")
+	holdout      = flag.Float64("holdout", 0.2, "fraction of infosets held out from training")
+	steps        = flag.Int("steps", 20000, "number of training steps")
+	batchSize    = flag.Int("batch", 32, "number of infosets in a batch")
+	fc           = flag.String("fc", "64,64", "comma separated sizes of the hidden layers")
+	learningRate = flag.Float64("learning_rate", 0.001, "learning rate of the optimizer")
+	logSteps     = flag.Int("log_steps", 1000, "number of steps between logs")
+	seed         = flag.Int64("seed", 0, "random seed")
+	savePath     = flag.String("save", "", "directory to save the network to")
+)
+
+type ModelConfig struct {
+	FC               []int   `json:"fc"`
+	FCNonlin         string  `json:"fc_nonlin"`
+	Optimizer        string  `json:"optimizer"`
+	LearningRate     float64 `json:"learning_rate"`
+	GradientClipping float64 `json:"gradient_clipping"`
+	NumFeatures      int     `json:"num_features"`
+	NumActions       int     `json:"num_actions"`
+}
+
+func getModelConfig(enc *features.Encoder) (*json.RawMessage, error) {
+	config := ModelConfig{
+		FCNonlin:         "relu",
+		Optimizer:        "adam",
+		LearningRate:     *learningRate,
+		GradientClipping: -1,
+		NumFeatures:      enc.Size(),
+		NumActions:       enc.NumActions(),
+	}
+	for _, s := range strings.Split(*fc, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, errors.Wrap(err, "Atoi")
+		}
+		config.FC = append(config.FC, size)
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "json.Marshal")
+	}
+	raw := json.RawMessage(b)
+	return &raw, nil
+}
+
+func createModel(config *json.RawMessage) (*awawtf.SavedModel, error) {
+	sessConf := &tfpb.ConfigProto{}
+	sessConfBytes, err := proto.Marshal(sessConf)
+	if err != nil {
+		return nil, errors.Wrap(err, "proto.Marshal")
+	}
+	sessOpt := &tf.SessionOptions{}
+	sessOpt.Config = sessConfBytes
+
+	modelBin := "github.com/fumin/bangbang/cfr/chapter3/distill/model.py"
+	model, err := awawtf.CreateModel(modelBin, config, sessOpt)
+	if err != nil {
+		return nil, errors.Wrap(err, "awawtf.CreateModel")
+	}
+	return model, nil
+}
+
+type Env struct {
+	batchSize int
+	inputsPH  tf.Output
+	maskPH    tf.Output
+	labelsPH  tf.Output
+	train     []distill.Example
+	test      []distill.Example
+}
+
+func NewEnv(model *awawtf.SavedModel, batchSize int, train, test []distill.Example) *Env {
+	g := model.Model.Graph
+	env := &Env{}
+	env.batchSize = batchSize
+	env.inputsPH = g.Operation("inputs").Output(0)
+	env.maskPH = g.Operation("mask").Output(0)
+	env.labelsPH = g.Operation("labels").Output(0)
+	env.train = train
+	env.test = test
+	return env
+}
+
+func (env *Env) feeds(examples []distill.Example) (map[tf.Output]*tf.Tensor, error) {
+	inputs, masks, labels := distill.Batch(examples)
+	inputsTF, err := tf.NewTensor(inputs)
+	if err != nil {
+		return nil, errors.Wrap(err, "tf.NewTensor")
+	}
+	masksTF, err := tf.NewTensor(masks)
+	if err != nil {
+		return nil, errors.Wrap(err, "tf.NewTensor")
+	}
+	labelsTF, err := tf.NewTensor(labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "tf.NewTensor")
+	}
+	feeds := make(map[tf.Output]*tf.Tensor)
+	feeds[env.inputsPH] = inputsTF
+	feeds[env.maskPH] = masksTF
+	feeds[env.labelsPH] = labelsTF
+	return feeds, nil
+}
+
+// Feeds returns a batch of training examples sampled with replacement.
+func (env *Env) Feeds() (map[tf.Output]*tf.Tensor, error) {
+	batch := make([]distill.Example, env.batchSize)
+	for b := range batch {
+		batch[b] = env.train[rand.Intn(len(env.train))]
+	}
+	return env.feeds(batch)
+}
+
+// TestFeeds returns all held out examples.
+func (env *Env) TestFeeds() (map[tf.Output]*tf.Tensor, error) {
+	return env.feeds(env.test)
+}
+
+type Agent struct {
+	model    *tf.SavedModel
+	inputsPH tf.Output
+	maskPH   tf.Output
+	stepIncr tf.Output
+	loss     tf.Output
+	probs    tf.Output
+	optimize *tf.Operation
+}
+
+func NewAgent(model *awawtf.SavedModel) *Agent {
+	g := model.Model.Graph
+	agent := &Agent{}
+	agent.model = model.Model
+	agent.inputsPH = g.Operation("inputs").Output(0)
+	agent.maskPH = g.Operation("mask").Output(0)
+	agent.stepIncr = g.Operation("step_incr").Output(0)
+	agent.loss = g.Operation("Model/loss").Output(0)
+	agent.probs = g.Operation("Model/probs").Output(0)
+	agent.optimize = g.Operation("Model/optimize")
+	return agent
+}
+
+type TrainOutput struct {
+	step int
+	loss float32
+}
+
+func (ag *Agent) Train(feeds map[tf.Output]*tf.Tensor) (*TrainOutput, error) {
+	fetches := []tf.Output{ag.stepIncr, ag.loss}
+	targets := []*tf.Operation{ag.optimize}
+	runRes, err := ag.model.Session.Run(feeds, fetches, targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "sess.Run")
+	}
+
+	output := &TrainOutput{}
+	output.step = int(runRes[0].Value().(int64))
+	output.loss = runRes[1].Value().(float32)
+	return output, nil
+}
+
+// Loss returns the loss of feeds without training.
+func (ag *Agent) Loss(feeds map[tf.Output]*tf.Tensor) (float32, error) {
+	runRes, err := ag.model.Session.Run(feeds, []tf.Output{ag.loss}, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "sess.Run")
+	}
+	return runRes[0].Value().(float32), nil
+}
+
+// Predict implements distill.Net.
+func (ag *Agent) Predict(inputs, masks [][]float32) ([][]float32, error) {
+	inputsTF, err := tf.NewTensor(inputs)
+	if err != nil {
+		return nil, errors.Wrap(err, "tf.NewTensor")
+	}
+	masksTF, err := tf.NewTensor(masks)
+	if err != nil {
+		return nil, errors.Wrap(err, "tf.NewTensor")
+	}
+	feeds := map[tf.Output]*tf.Tensor{ag.inputsPH: inputsTF, ag.maskPH: masksTF}
+	runRes, err := ag.model.Session.Run(feeds, []tf.Output{ag.probs}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "sess.Run")
+	}
+	return runRes[0].Value().([][]float32), nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+	rand.Seed(*seed)
+
+	f, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	root, err := f.NewGame()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if root.NumPlayers() != 2 {
+		glog.Fatalf("%d players", root.NumPlayers())
+	}
+	enc, err := features.NewEncoder(root)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	examples := distill.Examples(root, enc, f)
+	train, test := distill.Split(examples, *holdout)
+	if len(train) == 0 {
+		glog.Fatalf("no training examples out of %d infosets", len(examples))
+	}
+	glog.Infof("%d features, %d actions, %d training and %d held out infosets", enc.Size(), enc.NumActions(), len(train), len(test))
+
+	modelConfig, err := getModelConfig(enc)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	model, err := createModel(modelConfig)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	env := NewEnv(model, *batchSize, train, test)
+	agent := NewAgent(model)
+
+	start := time.Now()
+	for i := 0; i < *steps; i++ {
+		feeds, err := env.Feeds()
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		output, err := agent.Train(feeds)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+
+		if output.step%*logSteps == 0 {
+			val := map[string]string{"loss": fmt.Sprintf("%f", output.loss)}
+			if len(test) > 0 {
+				testFeeds, err := env.TestFeeds()
+				if err != nil {
+					glog.Fatalf("%+v", err)
+				}
+				testLoss, err := agent.Loss(testFeeds)
+				if err != nil {
+					glog.Fatalf("%+v", err)
+				}
+				val["test_loss"] = fmt.Sprintf("%f", testLoss)
+			}
+			glog.Infof("step: %d, val: %+v", output.step, val)
+		}
+	}
+	glog.Infof("trained %d steps in %s", *steps, time.Since(start))
+
+	glog.Infof("exploitability of the strategy: %.4f", exploit.Exploitability(root, f))
+	glog.Infof("exploitability of the network: %.4f", exploit.Exploitability(root, distill.NewPolicy(enc, agent)))
+
+	if *savePath != "" {
+		if err := awawtf.SaveModel(model, *savePath); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Command dudogame solves full 2 player Dudo games round by round,
+// and prints the win probabilities of every (myDices, oppDices) state.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/fsicfr"
+	"github.com/golang/glog"
+)
+
+var (
+	maxDices    = flag.Int("max_dices", 2, "number of dices each player starts with")
+	recall      = flag.Int("recall", 3, "number of most recent claims remembered")
+	iterations  = flag.Int("iterations", 100000, "number of FSICFR iterations for each state")
+	evalSamples = flag.Int("eval_samples", 10000, "number of sampled rolls to estimate each win probability")
+	seed        = flag.Int64("seed", 0, "seed of the rolls, random if zero")
+)
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	go func() {
+		glog.Fatal(http.ListenAndServe("localhost:6064", nil))
+	}()
+
+	var diceFaces uint8 = 6
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	src := chance.NewLive(*seed)
+
+	// winProb[m][o] is the probability that the player to start a round with m dices wins against o dices.
+	winProb := make([][]float64, *maxDices+1)
+	for m := range winProb {
+		winProb[m] = make([]float64, *maxDices+1)
+	}
+
+	// Every round loses at least one dice, so solve the states in increasing number of total dices.
+	for total := 2; total <= 2*(*maxDices); total++ {
+		for m := 1; m <= *maxDices; m++ {
+			o := total - m
+			if o < 1 || o > *maxDices {
+				continue
+			}
+
+			start := time.Now()
+			dices := [2]int{m, o}
+			utility := fsicfr.RoundUtility(dices, winProb)
+			round := fsicfr.NewRound(diceFaces, [2]uint8{uint8(m), uint8(o)}, *recall, utility, src)
+			for i := 0; i < *iterations; i++ {
+				if _, err := round.Iterate(); err != nil {
+					glog.Fatalf("%+v", err)
+				}
+			}
+			value, err := round.Value(*evalSamples)
+			if err != nil {
+				glog.Fatalf("%+v", err)
+			}
+			winProb[m][o] = value[0]
+			glog.Infof("state %dv%d: %d nodes, win probability %.4f, %s", m, o, round.NumNodes(), winProb[m][o], time.Since(start))
+		}
+	}
+
+	fmt.Printf("Win probability of the player to start, my dices in rows, opponent dices in columns:\n")
+	fmt.Printf("%4s", "")
+	for o := 1; o <= *maxDices; o++ {
+		fmt.Printf(" %6d", o)
+	}
+	fmt.Printf("\n")
+	for m := 1; m <= *maxDices; m++ {
+		fmt.Printf("%4d", m)
+		for o := 1; o <= *maxDices; o++ {
+			fmt.Printf(" %6.4f", winProb[m][o])
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,150 @@
+// Command exploit trains a counter-strategy to a modelled opponent in both seats,
+// and reports how much it wins against the model and how much it can lose in the worst case.
+//
+// The opponent is modelled by a strategy, a bot, or the frequencies of her actions in a log of match or acpcdealer:
+//
+//	exploit -strategy kuhn.gob -model call -p 1
+//	exploit -strategy dudo.gob -log match.log -opponent b -p 0.8 -save counter.gob
+//	exploit -strategy dudo.gob -log match.log -opponent b -p 0.8 -observations 10
+//
+// With -observations, the counter-strategy is a data biased response to the log,
+// in which the opponent plays the model at an infoset with a probability up to p that grows with its observations.
+// Otherwise, it is a best response if p is 1, and a restricted Nash response if not.
+//
+// The strategy tells the game, and serves as the baseline the counter-strategy is compared with.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/acpc"
+	"github.com/fumin/bangbang/cfr/chapter3/bots"
+	"github.com/fumin/bangbang/cfr/chapter3/exploit"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	strategyPath = flag.String("strategy", "", "path of the baseline strategy, which tells the game")
+	modelPath    = flag.String("model", "", "path of a strategy, or a bot, modelling the opponent")
+	logPath      = flag.String("log", "", "path of a match log, in which the opponent is modelled by her action frequencies")
+	opponent     = flag.String("opponent", "b", "name of the opponent in the log")
+	p            = flag.Float64("p", 1, "probability that the opponent plays the model in the restricted Nash response, which is a best response if 1")
+	observations = flag.Float64("observations", 0, "number of observations of an infoset at which a data biased response fully trusts the log")
+	iterations   = flag.Int("iterations", 10000, "number of CFR iterations of the restricted Nash or data biased response")
+	savePath     = flag.String("save", "", "path to save the counter-strategy to")
+)
+
+// loadModel returns the opponent model given by the flags.
+func loadModel(root tree.Game, baseline *strategy.File) (strategy.Policy, error) {
+	if *logPath != "" {
+		f, err := os.Open(*logPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "os.Open")
+		}
+		defer f.Close()
+		hands, err := acpc.ReadHands(f, root)
+		if err != nil {
+			return nil, errors.Wrap(err, "ReadHands")
+		}
+		model := exploit.NewModel(root)
+		for i, h := range hands {
+			if err := model.Observe(h, *opponent); err != nil {
+				return nil, errors.Wrapf(err, "hand %d", i)
+			}
+		}
+		if len(model.Counts) == 0 {
+			return nil, errors.Errorf("no actions of %s in %s", *opponent, *logPath)
+		}
+		glog.Infof("observed %d infosets of %s in %d hands", len(model.Counts), *opponent, len(hands))
+		return model, nil
+	}
+
+	if bots.IsBot(*modelPath) {
+		return bots.New(*modelPath)
+	}
+	model, err := strategy.Load(*modelPath)
+	if err != nil {
+		return nil, errors.Wrap(err, *modelPath)
+	}
+	if !model.SameGame(baseline) {
+		return nil, errors.Errorf("%s is of a different game than %s", *modelPath, *strategyPath)
+	}
+	return model, nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	baseline, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	root, err := baseline.NewGame()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if root.NumPlayers() != 2 {
+		glog.Fatalf("%d players", root.NumPlayers())
+	}
+	model, err := loadModel(root, baseline)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	confidence := exploit.Confidence{PMax: *p, Observations: *observations}
+	if *observations > 0 {
+		if err := confidence.Validate(); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		if _, ok := model.(*exploit.Model); !ok {
+			glog.Fatalf("data biased responses need a model from -log")
+		}
+	}
+
+	// Train a counter-strategy in each seat, and merge them into one strategy, as infosets belong to one player.
+	start := time.Now()
+	counter := *baseline
+	counter.Strategies = make(map[string][]float64)
+	for player := 0; player < root.NumPlayers(); player++ {
+		var strategies map[string][]float64
+		switch {
+		case *observations > 0:
+			strategies = exploit.DataBiased(root, player, model.(*exploit.Model), confidence, *iterations)
+		case *p == 1:
+			strategies, _, err = exploit.BestResponse(root, player, model)
+			if err != nil {
+				glog.Fatalf("%+v", err)
+			}
+		default:
+			strategies = exploit.RestrictedNash(root, player, model, *p, *iterations)
+		}
+		for infoset, s := range strategies {
+			counter.Strategies[infoset] = s
+		}
+	}
+	glog.Infof("trained counter-strategy with p %.2f in %s", *p, time.Since(start))
+
+	// Payoffs are averaged over the seats, as in match.
+	for _, s := range []struct {
+		name   string
+		policy *strategy.File
+	}{{"baseline", baseline}, {"counter-strategy", &counter}} {
+		var won, worst float64
+		for player := 0; player < root.NumPlayers(); player++ {
+			won += exploit.Value(root, player, s.policy, model) / 2
+			worst -= exploit.BestResponseValue(root, 1-player, s.policy) / 2
+		}
+		glog.Infof("%s: %.4f against the model, %.4f in the worst case", s.name, won, worst)
+	}
+
+	if *savePath != "" {
+		if err := strategy.Save(*savePath, &counter); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Command match plays two strategies saved by treecfr or vcfr, or heuristic bots, against each other,
+// and reports the mean payoff of the first with a 95% confidence interval.
+//
+//	match -a kuhn_1000.gob -b kuhn_100000.gob -games 100000 -duplicate
+//	match -a dudo.gob -b dudo:0.6
+//	match -a ismcts -b dudo.gob -belief dudo.gob -simulations 2000
+//	match -a resolve -b dudo.gob -blueprint dudo.gob -depth 2
+//	match -a dudo.gob -b dudo.gob -record selfplay.rec
+//
+// Bots are named as in bots.New, ismcts names an agent of package ismcts,
+// and resolve names a resolver of package resolve, which refines the strategy at -blueprint.
+// They all play the game of the strategy.
+// Playing a strategy against itself with -record records self play games, which the command replay prints.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/bots"
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/ismcts"
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/record"
+	"github.com/fumin/bangbang/cfr/chapter3/resolve"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	aPath      = flag.String("a", "", "path of the first strategy, ismcts, resolve, or a bot, "+strings.Join(bots.Names, ", "))
+	bPath      = flag.String("b", "random", "path of the second strategy, or a bot")
+	games      = flag.Int("games", 10000, "number of games, which must be even, or of pairs of games when dealing duplicate")
+	duplicate  = flag.Bool("duplicate", false, "replay each deal with the seats swapped")
+	luckPath   = flag.String("luck", "", "path of a strategy, or a bot, whose self play values are subtracted as the luck of chance")
+	logPath    = flag.String("log", "", "path to log the games to, for evaluation with aivat")
+	recordPath = flag.String("record", "", "path to record the games to, with the probabilities of every decision")
+
+	seed         = flag.Int64("seed", 0, "seed of the chance outcomes and of the searches of ismcts, random if zero")
+	tracePath    = flag.String("chance", "", "path of a trace of chance outcomes to replay, such as recorded by -record_chance")
+	recordChance = flag.String("record_chance", "", "path to record the chance outcomes to, for replaying the same deals with -chance")
+
+	simulations = flag.Int("simulations", ismcts.DefaultConfig().Simulations, "number of simulations of ismcts per decision")
+	exploration = flag.Float64("exploration", ismcts.DefaultConfig().Exploration, "UCT exploration constant of ismcts")
+	beliefPath  = flag.String("belief", "", "path of a strategy, or a bot, modelling the opponents of ismcts")
+
+	blueprintPath     = flag.String("blueprint", "", "path of the blueprint strategy of resolve, such as saved by section3.5 -save")
+	resolveIterations = flag.Int("resolve_iterations", resolve.DefaultConfig().Iterations, "number of CFR iterations of resolve per decision")
+	depth             = flag.Int("depth", resolve.DefaultConfig().Depth, "number of actions resolve looks ahead before valuing states by the blueprint, unlimited if 0")
+	safe              = flag.Bool("safe", resolve.DefaultConfig().Safe, "resolve with the safe re-solving gadget")
+)
+
+const (
+	// searcher is the name of the ismcts agent, which searches online instead of playing a saved strategy.
+	searcher = "ismcts"
+	// resolver is the name of the subgame resolver, which refines the blueprint online.
+	resolver = "resolve"
+)
+
+// newSearcher returns an ismcts agent configured by the flags.
+func newSearcher(root tree.Game) (strategy.Policy, error) {
+	cfg := ismcts.Config{Simulations: *simulations, Exploration: *exploration, Seed: *seed}
+	switch {
+	case bots.IsBot(*beliefPath):
+		belief, err := bots.New(*beliefPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "New")
+		}
+		cfg.Belief = belief
+	case *beliefPath != "":
+		belief, err := strategy.Load(*beliefPath)
+		if err != nil {
+			return nil, errors.Wrap(err, *beliefPath)
+		}
+		cfg.Belief = belief
+	}
+	agent, err := ismcts.NewAgent(root, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewAgent")
+	}
+	return agent, nil
+}
+
+// newResolver returns a resolver configured by the flags.
+func newResolver(root tree.Game) (strategy.Policy, error) {
+	blueprint, err := strategy.Load(*blueprintPath)
+	if err != nil {
+		return nil, errors.Wrap(err, *blueprintPath)
+	}
+	cfg := resolve.Config{Iterations: *resolveIterations, Depth: *depth, Safe: *safe}
+	r, err := resolve.NewResolver(root, blueprint, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewResolver")
+	}
+	return r, nil
+}
+
+// load loads the strategies, bots, ismcts agents or resolvers at paths, and returns the game of the strategies and its root.
+func load(paths ...string) ([]strategy.Policy, *strategy.File, tree.Game, error) {
+	policies := make([]strategy.Policy, 0, len(paths))
+	var game *strategy.File
+	for _, path := range paths {
+		if path == searcher || path == resolver {
+			// Searchers and resolvers are created once the game is known.
+			policies = append(policies, nil)
+			continue
+		}
+		if bots.IsBot(path) {
+			bot, err := bots.New(path)
+			if err != nil {
+				return nil, nil, nil, errors.Wrap(err, "New")
+			}
+			policies = append(policies, bot)
+			continue
+		}
+
+		f, err := strategy.Load(path)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, path)
+		}
+		if game != nil && !f.SameGame(game) {
+			return nil, nil, nil, errors.Errorf("%s is of a different game than the other strategies", path)
+		}
+		game = f
+		policies = append(policies, f)
+	}
+	if game == nil {
+		return nil, nil, nil, errors.Errorf("no strategy in %v to tell the game", paths)
+	}
+	root, err := game.NewGame()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "NewGame")
+	}
+	for i, path := range paths {
+		switch path {
+		case searcher:
+			policies[i], err = newSearcher(root)
+		case resolver:
+			policies[i], err = newResolver(root)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return policies, game, root, nil
+}
+
+// run plays the match of the flags, and flushes the logs and records of the games played even if the match fails.
+func run() error {
+	paths := []string{*aPath, *bPath}
+	if *luckPath != "" {
+		paths = append(paths, *luckPath)
+	}
+	policies, game, root, err := load(paths...)
+	if err != nil {
+		return errors.Wrap(err, "load")
+	}
+
+	cfg := match.Config{Games: *games, Duplicate: *duplicate}
+	if len(policies) > 2 {
+		cfg.Luck = policies[2]
+	}
+	// writers are flushed once the match ends.
+	writers := make([]*bufio.Writer, 0)
+	if *logPath != "" {
+		log, err := os.Create(*logPath)
+		if err != nil {
+			return errors.Wrap(err, "os.Create")
+		}
+		defer log.Close()
+		w := bufio.NewWriter(log)
+		writers = append(writers, w)
+		cfg.Log = w
+	}
+	if *seed != 0 {
+		cfg.Chance = chance.NewLive(*seed)
+	}
+	if *tracePath != "" {
+		f, err := os.Open(*tracePath)
+		if err != nil {
+			return errors.Wrap(err, "os.Open")
+		}
+		defer f.Close()
+		cfg.Chance = chance.NewTrace(f, nil)
+	}
+	if *recordChance != "" {
+		if cfg.Chance == nil {
+			cfg.Chance = chance.NewLive(time.Now().UnixNano())
+		}
+		f, err := os.Create(*recordChance)
+		if err != nil {
+			return errors.Wrap(err, "os.Create")
+		}
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		writers = append(writers, w)
+		cfg.Chance = chance.NewRecorder(cfg.Chance, w, nil)
+	}
+	if *recordPath != "" {
+		f, err := os.Create(*recordPath)
+		if err != nil {
+			return errors.Wrap(err, "os.Create")
+		}
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		writers = append(writers, w)
+		records, err := record.NewWriter(w, game)
+		if err != nil {
+			return errors.Wrap(err, "NewWriter")
+		}
+		cfg.Record = records.Write
+	}
+
+	start := time.Now()
+	result, runErr := match.Run(root, policies[0], policies[1], cfg)
+	for _, w := range writers {
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "Flush")
+		}
+	}
+	if errors.Cause(runErr) == chance.ErrEnd {
+		glog.Infof("chance outcomes ended: %v", runErr)
+	} else if runErr != nil {
+		return errors.Wrap(runErr, "Run")
+	}
+	glog.Infof("%s against %s: %s, in %s", *aPath, *bPath, result, time.Since(start))
+	return nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	if err := run(); err != nil {
+		glog.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Command play deals Kuhn poker or Leduc Hold'em, or rolls Dudo, for a human to play against a strategy saved by treecfr or vcfr.
+//
+// Actions are typed as "pass", "bet" and "raise" in Kuhn, "fold", "call" and "raise" in Leduc, and as claims such as "3x5", "dudo" and "calza" in Dudo.
+// The human takes turns in each seat, and all other seats are played by sampling from the average strategy,
+// or by a bot of package bots if -bot is given.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/bots"
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	strategyPath = flag.String("strategy", "", "path of the strategy saved by treecfr or vcfr")
+	botName      = flag.String("bot", "", "bot to play against in the game of the strategy, "+strings.Join(bots.Names, ", "))
+	seed         = flag.Int64("seed", 0, "seed of the deals, random if zero")
+	tracePath    = flag.String("chance", "", "path of a trace of deals to play, such as recorded by match -record_chance")
+)
+
+// readAction reads the action of the human until she types a valid one.
+func readAction(in *bufio.Scanner, names []string) (int, error) {
+	for {
+		fmt.Printf("Your action [%s]: ", strings.Join(names, " "))
+		if !in.Scan() {
+			if err := in.Err(); err != nil {
+				return -1, errors.Wrap(err, "Scan")
+			}
+			return -1, io.EOF
+		}
+		input := strings.ToLower(strings.TrimSpace(in.Text()))
+		if input == "quit" {
+			return -1, io.EOF
+		}
+		for aIdx, name := range names {
+			if input == name {
+				return aIdx, nil
+			}
+		}
+		fmt.Printf("Invalid action %q\n", input)
+	}
+}
+
+// play plays one game with the human in seat human, and returns the payoffs.
+func play(opponent strategy.Policy, root tree.Game, human int, src chance.Source, in *bufio.Scanner) ([]float64, error) {
+	game := root
+	for !game.IsTerminal() {
+		if game.IsChanceNode() {
+			o, err := src.Outcome(game)
+			if err != nil {
+				return nil, errors.Wrap(err, "Outcome")
+			}
+			game = game.Chance(o)
+			fmt.Printf("You are player %d with %s\n", human, strategy.Private(game, human))
+			continue
+		}
+
+		player := game.CurPlayer()
+		names := strategy.ActionNames(game)
+		var aIdx int
+		if player == human {
+			var err error
+			aIdx, err = readAction(in, names)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			aIdx = strategy.SampleAction(opponent.Probs(game))
+			fmt.Printf("Player %d: %s\n", player, names[aIdx])
+		}
+		game = game.Play(aIdx)
+	}
+
+	for p := 0; p < game.NumPlayers(); p++ {
+		if p != human {
+			fmt.Printf("Player %d had %s\n", p, strategy.Private(game, p))
+		}
+	}
+	payoff := make([]float64, game.NumPlayers())
+	game.Payoff(payoff)
+	return payoff, nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	file, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	root, err := file.NewGame()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+
+	var opponent strategy.Policy = file
+	if *botName != "" {
+		opponent, err = bots.New(*botName)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	var src chance.Source = chance.NewLive(*seed)
+	if *tracePath != "" {
+		f, err := os.Open(*tracePath)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		defer f.Close()
+		src = chance.NewTrace(f, nil)
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	var score float64 = 0
+	for i := 1; ; i++ {
+		human := (i - 1) % root.NumPlayers()
+		fmt.Printf("\nGame %d\n", i)
+		payoff, err := play(opponent, root, human, src, in)
+		if err == io.EOF {
+			break
+		}
+		if errors.Cause(err) == chance.ErrEnd {
+			fmt.Printf("No more deals\n")
+			break
+		}
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+
+		score += payoff[human]
+		fmt.Printf("You got %g, score %g after %d games\n", payoff[human], score, i)
+	}
+}
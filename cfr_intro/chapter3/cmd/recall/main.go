@@ -0,0 +1,85 @@
+// Command recall trains 1v1 Dudo under imperfect recall abstractions that remember only the last k claims,
+// and reports the exploitability of each abstracted strategy in the unabstracted game.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/exploit"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+)
+
+var (
+	recalls     = flag.String("recalls", "1,2,3,0", "comma separated numbers of remembered claims, where 0 means perfect recall")
+	iterations  = flag.Int("iterations", 2000, "number of CFR iterations for each abstraction")
+	playerDices = flag.String("dices", "1,1", "comma separated number of dices of each player")
+	diceFaces   = flag.Int("faces", 6, "number of faces of dices")
+)
+
+type result struct {
+	recall         int
+	numInfosets    int
+	exploitability float64
+}
+
+func train(game dudo.Dudo) result {
+	root := games.Dudo{State: game}
+	t := tree.Build(root)
+	c := tree.NewCFR(t)
+	logEvery := *iterations / 10
+	if logEvery < 1 {
+		logEvery = 1
+	}
+	for i := 1; i <= *iterations; i++ {
+		c.Iterate()
+
+		if i%logEvery == 0 {
+			expl := exploit.Exploitability(root, &strategy.File{Strategies: c.AvgStrategies()})
+			glog.Infof("recall %d, iteration %d: exploitability %f", game.Recall, i, expl)
+		}
+	}
+
+	res := result{
+		recall:         game.Recall,
+		numInfosets:    t.NumInfosets(),
+		exploitability: exploit.Exploitability(root, &strategy.File{Strategies: c.AvgStrategies()}),
+	}
+	return res
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	numDices, err := dudo.ParseNumDices(*playerDices)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	rules := dudo.DefaultRules(uint8(*diceFaces))
+
+	results := make([]result, 0)
+	for _, s := range strings.Split(*recalls, ",") {
+		recall, err := strconv.Atoi(s)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		game, err := dudo.NewDudoRules(rules, numDices)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		game.Recall = recall
+		results = append(results, train(game))
+	}
+
+	fmt.Printf("%6s %8s %14s\n", "recall", "infosets", "exploitability")
+	for _, res := range results {
+		fmt.Printf("%6d %8d %14.6f\n", res.recall, res.numInfosets, res.exploitability)
+	}
+}
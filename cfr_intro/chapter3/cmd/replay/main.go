@@ -0,0 +1,88 @@
+// Command replay prints the games of a record file written by match -record,
+// with the cards or dices dealt, and every action together with the probabilities it was chosen from.
+//
+//	replay -records selfplay.rec -games 10
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/record"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+)
+
+var (
+	recordsPath = flag.String("records", "", "path of the record file")
+	skip        = flag.Int("skip", 0, "number of games to skip")
+	games       = flag.Int("games", 0, "number of games to print, all if 0")
+)
+
+// printHand prints a hand from its states.
+func printHand(w io.Writer, i int, h match.Hand, states []tree.Game) {
+	fmt.Fprintf(w, "Game %d: %s\n", i, strings.Join(h.Players, " vs "))
+	for s, aIdx := range h.Steps {
+		game := states[s]
+		if game.IsChanceNode() {
+			privates := make([]string, 0, game.NumPlayers())
+			for p := 0; p < game.NumPlayers(); p++ {
+				privates = append(privates, fmt.Sprintf("player %d has %s", p, strategy.Private(states[s+1], p)))
+			}
+			fmt.Fprintf(w, "  chance: %s\n", strings.Join(privates, ", "))
+			continue
+		}
+
+		player := game.CurPlayer()
+		names := strategy.ActionNames(game)
+		probs := make([]string, 0, len(names))
+		if s < len(h.Probs) {
+			for a, p := range h.Probs[s] {
+				probs = append(probs, fmt.Sprintf("%s %.2f", names[a], p))
+			}
+		}
+		fmt.Fprintf(w, "  %s (player %d at %s): %s [%s]\n", h.Players[player], player, strategy.InfosetName(game), names[aIdx], strings.Join(probs, ", "))
+	}
+
+	payoffs := make([]string, 0, len(h.Payoffs))
+	for p, u := range h.Payoffs {
+		payoffs = append(payoffs, fmt.Sprintf("%s %g", h.Players[p], u))
+	}
+	fmt.Fprintf(w, "  payoffs: %s\n", strings.Join(payoffs, ", "))
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	f, err := os.Open(*recordsPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	defer f.Close()
+	rd, err := record.NewReader(bufio.NewReader(f))
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for i := 0; *games == 0 || i < *skip+*games; i++ {
+		h, states, err := rd.Replay()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			glog.Fatalf("game %d: %+v", i, err)
+		}
+		if i >= *skip {
+			printHand(w, i, h, states)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Command serve serves a strategy saved by treecfr or vcfr as a JSON API on localhost.
+//
+//	curl 'localhost:6065/infosets?player=0'
+//	curl 'localhost:6065/infoset?name=2pb'
+//	curl 'localhost:6065/sample?name=2pb'
+//
+// The pprof handlers are served on the same port.
+package main
+
+import (
+	"flag"
+	"net/http"
+	_ "net/http/pprof"
+
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/golang/glog"
+)
+
+var (
+	strategyPath = flag.String("strategy", "", "path of the strategy saved by treecfr or vcfr")
+	addr         = flag.String("addr", "localhost:6065", "address to serve on")
+)
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	file, err := strategy.Load(*strategyPath)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	handler, err := strategy.NewHandler(file)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+
+	http.Handle("/infoset", handler)
+	http.Handle("/sample", handler)
+	http.Handle("/infosets", handler)
+	glog.Infof("serving %s strategy on %s", file.Game, *addr)
+	glog.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,272 @@
+// Command treecfr builds the whole tree of a small game such as Kuhn poker, Leduc Hold'em, Goofspiel, Oshi-Zumo, Dudo or a matrix game once,
+// and trains it with iterative CFR sweeps over the precomputed tree.
+//
+//	treecfr -game kuhn -iterations 100000 -save kuhn.gob -record selfplay.rec
+//
+// With -record, the average strategy plays -record_games games against itself after training,
+// and the games are recorded with the probabilities of every decision, for the command replay or for training learned models.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/goofspiel"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/matrix"
+	"github.com/fumin/bangbang/cfr/chapter3/oshizumo"
+	"github.com/fumin/bangbang/cfr/chapter3/record"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	gameName    = flag.String("game", "dudo", "game to train, kuhn, leduc, goofspiel, oshizumo, dudo or matrix")
+	iterations  = flag.Int("iterations", 10000, "number of CFR iterations")
+	playerDices = flag.String("dices", "1,1", "comma separated number of dices of each Dudo player")
+	diceFaces   = flag.Int("faces", 6, "number of faces of Dudo dices")
+	wildOnes    = flag.Bool("wild_ones", true, "whether ones are wild in Dudo")
+	palifico    = flag.Bool("palifico", false, "play a Dudo palifico round")
+	calza       = flag.Bool("calza", false, "allow calling Dudo claims exact")
+	ordering    = flag.String("ordering", "", "ordering of Dudo claims, neller or count, neller if ones are wild and count otherwise if empty")
+	numCards    = flag.Int("cards", 3, "number of cards in the Kuhn deck, or in each Goofspiel hand")
+	numPlayers  = flag.Int("players", 2, "number of Kuhn players")
+	ante        = flag.Int("ante", 1, "ante of each Kuhn player")
+	betSize     = flag.Int("bet", 1, "size of Kuhn bets and raises")
+	maxBets     = flag.Int("max_bets", 1, "number of Kuhn bets and raises allowed")
+	coins       = flag.Int("coins", 4, "number of Oshi-Zumo coins of each player")
+	fieldSize   = flag.Int("field", 2, "number of Oshi-Zumo locations on each side of the middle")
+	minBid      = flag.Int("min_bid", 1, "minimum Oshi-Zumo bid")
+	payoff      = flag.String("payoff", "", "payoff matrix of the row player, such as \"1,-1;-1,1\", rock paper scissors if empty")
+	tolerance   = flag.Float64("tolerance", 0.01, "largest difference allowed between the value of the average strategy and the known value of the game")
+	savePath    = flag.String("save", "", "path to save the Kuhn, Leduc, Dudo or matrix game strategy to")
+	recordPath  = flag.String("record", "", "path to record self play games of the Kuhn, Leduc, Dudo or matrix game strategy to")
+	recordGames = flag.Int("record_games", 10000, "number of self play games to record, which must be even as the seats alternate")
+)
+
+func parseRules() (dudo.Rules, error) {
+	rules := dudo.Rules{
+		DiceFaces: uint8(*diceFaces),
+		WildOnes:  *wildOnes,
+		Palifico:  *palifico,
+		Calza:     *calza,
+	}
+	rules.Ordering = rules.DefaultOrdering()
+	if *ordering != "" {
+		ordering, err := dudo.ParseOrdering(*ordering)
+		if err != nil {
+			return dudo.Rules{}, errors.Wrap(err, "ParseOrdering")
+		}
+		rules.Ordering = ordering
+	}
+	return rules, nil
+}
+
+func fmtFloatSlice(fs []float64, precision int) string {
+	ss := make([]string, 0, len(fs))
+	for _, f := range fs {
+		fmtStr := fmt.Sprintf("%%.%df", precision)
+		ss = append(ss, fmt.Sprintf(fmtStr, f))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(ss, " "))
+}
+
+func printStrategy(c *tree.CFR, fmtInfoset func(string) string) {
+	t := c.Tree
+	playerInfoset := make([][]int, t.NumPlayers)
+	for is := 0; is < t.NumInfosets(); is++ {
+		player := t.InfosetPlayer[is]
+		playerInfoset[player] = append(playerInfoset[player], is)
+	}
+
+	for player, infosets := range playerInfoset {
+		sort.Slice(infosets, func(i, j int) bool {
+			return t.InfosetKey[infosets[i]] < t.InfosetKey[infosets[j]]
+		})
+		fmt.Printf("Player %d infosets:\n", player)
+		for _, is := range infosets {
+			fmt.Printf("%6s: %s\n", fmtInfoset(t.InfosetKey[is]), fmtFloatSlice(c.AvgStrategy(is), 2))
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// recordSelfPlay records games of the strategy of f against itself.
+func recordSelfPlay(path string, root tree.Game, f *strategy.File, games int) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "os.Create")
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	records, err := record.NewWriter(w, f)
+	if err != nil {
+		return errors.Wrap(err, "NewWriter")
+	}
+	cfg := match.Config{Games: games, Record: records.Write}
+	if _, err := match.Run(root, f, f, cfg); err != nil {
+		return errors.Wrap(err, "Run")
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "Flush")
+	}
+	return nil
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	go func() {
+		glog.Fatal(http.ListenAndServe("localhost:6062", nil))
+	}()
+
+	var root tree.Game
+	fmtInfoset := func(s string) string { return s }
+	// value is the known value of the game for the first player, if any.
+	value := math.NaN()
+	// file describes the game for saving the strategy.
+	file := &strategy.File{}
+	switch *gameName {
+	case "kuhn":
+		cfg := kuhn.Config{
+			NumCards:   *numCards,
+			NumPlayers: *numPlayers,
+			Ante:       *ante,
+			BetSize:    *betSize,
+			MaxBets:    *maxBets,
+		}
+		game, err := kuhn.NewKuhnConfig(cfg)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		root = games.Kuhn{State: game}
+		file.Game = strategy.Kuhn
+		file.Kuhn = cfg
+		if cfg == kuhn.DefaultConfig() {
+			value = kuhn.Value
+		}
+	case "goofspiel":
+		game, err := goofspiel.NewGoofspiel(*numCards)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		root = games.Goofspiel{State: game}
+		value = goofspiel.Value
+	case "oshizumo":
+		cfg := oshizumo.Config{Coins: *coins, FieldSize: *fieldSize, MinBid: *minBid}
+		game, err := oshizumo.NewOshiZumo(cfg)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		root = games.OshiZumo{State: game}
+		value = oshizumo.Value
+	case "leduc":
+		root = games.Leduc{State: leduc.NewLeduc()}
+		value = leduc.Value
+		file.Game = strategy.Leduc
+	case "matrix":
+		cfg := matrix.RPS()
+		if *payoff != "" {
+			p, err := matrix.ParsePayoff(*payoff)
+			if err != nil {
+				glog.Fatalf("%+v", err)
+			}
+			cfg = matrix.Config{Payoff: p}
+		} else {
+			value = matrix.RPSValue
+		}
+		game, err := matrix.NewMatrix(cfg)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		root = games.Matrix{State: game}
+		file.Game = strategy.Matrix
+		file.Matrix = cfg
+	case "dudo":
+		numDices, err := dudo.ParseNumDices(*playerDices)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		rules, err := parseRules()
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		game, err := dudo.NewDudoRules(rules, numDices)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		root = games.Dudo{State: game}
+		fmtInfoset = game.FormatInfoset
+		file.Game = strategy.Dudo
+		file.DudoRules = rules
+		file.NumDices = numDices
+	default:
+		glog.Fatalf("unknown game %s", *gameName)
+	}
+
+	start := time.Now()
+	t := tree.Build(root)
+	glog.Infof("built tree of %d nodes and %d infosets in %s", t.NumNodes(), t.NumInfosets(), time.Since(start))
+
+	c := tree.NewCFR(t)
+	logEvery := *iterations / 100
+	if logEvery < 1 {
+		logEvery = 1
+	}
+	utilSum := make([]float64, t.NumPlayers)
+	start = time.Now()
+	for i := 1; i <= *iterations; i++ {
+		util := c.Iterate()
+		for p, u := range util {
+			utilSum[p] += u
+		}
+
+		if i%logEvery == 0 {
+			avg := make([]float64, len(utilSum))
+			for p, s := range utilSum {
+				avg[p] = s / float64(i)
+			}
+			itersPerSec := float64(i) / time.Since(start).Seconds()
+			glog.Infof("util %d: %s, %.0f iterations/s", i, fmtFloatSlice(avg, 6), itersPerSec)
+		}
+	}
+
+	if !math.IsNaN(value) {
+		avgValue := match.Value(root, &strategy.File{Strategies: c.AvgStrategies()})[0]
+		glog.Infof("util of the first player %.6f, value of the average strategy %.6f, known value %.6f", utilSum[0]/float64(*iterations), avgValue, value)
+		if math.Abs(avgValue-value) > *tolerance {
+			glog.Fatalf("value of the average strategy %.6f is off the known value %.6f by more than %g", avgValue, value, *tolerance)
+		}
+	}
+	printStrategy(c, fmtInfoset)
+
+	if (*savePath != "" || *recordPath != "") && file.Game == "" {
+		glog.Fatalf("saving %s strategies is not supported", *gameName)
+	}
+	file.Strategies = c.AvgStrategies()
+	if *savePath != "" {
+		if err := strategy.Save(*savePath, file); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+	if *recordPath != "" {
+		if err := recordSelfPlay(*recordPath, root, file, *recordGames); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Command vcfr trains 1v1 Dudo with vectorised CFR over the public claim tree.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/vcfr"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var (
+	iterations  = flag.Int("iterations", 10000, "number of CFR iterations")
+	playerDices = flag.String("dices", "1,1", "comma separated number of dices of each of the two players")
+	diceFaces   = flag.Int("faces", 6, "number of faces of dices")
+	wildOnes    = flag.Bool("wild_ones", true, "whether ones are wild")
+	palifico    = flag.Bool("palifico", false, "play a palifico round")
+	calza       = flag.Bool("calza", false, "allow calling claims exact")
+	ordering    = flag.String("ordering", "", "ordering of claims, neller or count, neller if ones are wild and count otherwise if empty")
+	savePath    = flag.String("save", "", "path to save the strategy to")
+)
+
+func parseRules() (dudo.Rules, error) {
+	rules := dudo.Rules{
+		DiceFaces: uint8(*diceFaces),
+		WildOnes:  *wildOnes,
+		Palifico:  *palifico,
+		Calza:     *calza,
+	}
+	rules.Ordering = rules.DefaultOrdering()
+	if *ordering != "" {
+		ordering, err := dudo.ParseOrdering(*ordering)
+		if err != nil {
+			return dudo.Rules{}, errors.Wrap(err, "ParseOrdering")
+		}
+		rules.Ordering = ordering
+	}
+	return rules, nil
+}
+
+func fmtFloatSlice(fs []float64, precision int) string {
+	ss := make([]string, 0, len(fs))
+	for _, f := range fs {
+		fmtStr := fmt.Sprintf("%%.%df", precision)
+		ss = append(ss, fmt.Sprintf(fmtStr, f))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(ss, " "))
+}
+
+func printStrategies(game dudo.Dudo, strategies map[string][]float64) {
+	// Create the infosets for each player.
+	playerInfoset := make([][]string, game.NumPlayers())
+	for infoset := range strategies {
+		_, player, _, err := game.ParseInfoset(infoset)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
+
+		playerInfoset[player] = append(playerInfoset[player], infoset)
+	}
+	for _, pis := range playerInfoset {
+		sort.Strings(pis)
+	}
+
+	for player, infosets := range playerInfoset {
+		fmt.Printf("Player %d infosets:\n", player)
+		for _, is := range infosets {
+			fmt.Printf("%6s: %s\n", game.FormatInfoset(is), fmtFloatSlice(strategies[is], 2))
+		}
+		fmt.Printf("\n")
+	}
+}
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	go func() {
+		glog.Fatal(http.ListenAndServe("localhost:6063", nil))
+	}()
+
+	numDices, err := dudo.ParseNumDices(*playerDices)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	if len(numDices) != 2 {
+		glog.Fatalf("vectorised CFR needs 2 players, got %d", len(numDices))
+	}
+	rules, err := parseRules()
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	game, err := dudo.NewDudoRules(rules, numDices)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
+	glog.Infof("Claims: %+v", game.Claims())
+
+	v := vcfr.New(game)
+	logEvery := *iterations / 100
+	if logEvery < 1 {
+		logEvery = 1
+	}
+	var utilSum float64 = 0
+	start := time.Now()
+	for i := 1; i <= *iterations; i++ {
+		utilSum += v.Iterate()
+
+		if i%logEvery == 0 {
+			itersPerSec := float64(i) / time.Since(start).Seconds()
+			glog.Infof("util %d: %.6f, %.0f iterations/s", i, utilSum/float64(i), itersPerSec)
+		}
+	}
+
+	strategies := v.AvgStrategies()
+	printStrategies(game, strategies)
+
+	if *savePath != "" {
+		file := &strategy.File{
+			Game:       strategy.Dudo,
+			DudoRules:  rules,
+			NumDices:   numDices,
+			Strategies: strategies,
+		}
+		if err := strategy.Save(*savePath, file); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package distill clones a trained strategy into a policy network, by supervised learning of the average strategy at every infoset.
+//
+// The network is trained with a cross-entropy loss on examples of (infoset features, average strategy),
+// and plays through a softmax over the action slots of package features, masked to the legal actions.
+// Holding out some infosets from training measures how the network generalizes to infosets it has never seen.
+package distill
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/fumin/bangbang/cfr/chapter3/features"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+)
+
+// Example is the strategy of an infoset, encoded for a network.
+type Example struct {
+	Infoset  string
+	Features []float32
+	// Mask is 1 at the slots of the legal actions, and 0 elsewhere.
+	Mask []float32
+	// Probs are the probabilities of each action slot.
+	Probs []float32
+}
+
+// Examples returns an example of every infoset reachable from root, with the strategy of policy.
+func Examples(root tree.Game, enc *features.Encoder, policy strategy.Policy) []Example {
+	examples := make([]Example, 0)
+	seen := make(map[string]bool)
+	var walk func(game tree.Game)
+	walk = func(game tree.Game) {
+		if game.IsTerminal() {
+			return
+		}
+		if game.IsChanceNode() {
+			for o := 0; o < game.ChanceLen(); o++ {
+				walk(game.Chance(o))
+			}
+			return
+		}
+
+		if infoset := game.Infoset(); !seen[infoset] {
+			seen[infoset] = true
+			examples = append(examples, example(enc, game, policy.Probs(game)))
+		}
+		for a := 0; a < game.ActionsLen(); a++ {
+			walk(game.Play(a))
+		}
+	}
+	walk(root)
+	return examples
+}
+
+func example(enc *features.Encoder, game tree.Game, probs []float64) Example {
+	ex := Example{
+		Infoset:  game.Infoset(),
+		Features: make([]float32, enc.Size()),
+		Mask:     make([]float32, enc.NumActions()),
+		Probs:    make([]float32, enc.NumActions()),
+	}
+	enc.Encode(game, ex.Features)
+	enc.Mask(game, ex.Mask)
+	slots := make([]int, game.ActionsLen())
+	enc.Actions(game, slots)
+	for i, s := range slots {
+		ex.Probs[s] = float32(probs[i])
+	}
+	return ex
+}
+
+// Split shuffles examples, and holds out a fraction of them for testing.
+func Split(examples []Example, holdout float64) (train, test []Example) {
+	shuffled := make([]Example, len(examples))
+	copy(shuffled, examples)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	numTest := int(math.Round(holdout * float64(len(shuffled))))
+	return shuffled[numTest:], shuffled[:numTest]
+}
+
+// Batch returns the features, masks and probabilities of examples as matrices, which are fed to a network.
+func Batch(examples []Example) (inputs, masks, labels [][]float32) {
+	for _, ex := range examples {
+		inputs = append(inputs, ex.Features)
+		masks = append(masks, ex.Mask)
+		labels = append(labels, ex.Probs)
+	}
+	return inputs, masks, labels
+}
+
+// Net is a policy network, which returns the probabilities of the action slots of a batch of features and masks.
+type Net interface {
+	Predict(inputs, masks [][]float32) ([][]float32, error)
+}
+
+// Policy plays the strategy of a network, and remembers the strategy of every infoset it is asked.
+type Policy struct {
+	enc    *features.Encoder
+	net    Net
+	probs  map[string][]float64
+	inputs []float32
+	mask   []float32
+}
+
+func NewPolicy(enc *features.Encoder, net Net) *Policy {
+	p := &Policy{
+		enc:    enc,
+		net:    net,
+		probs:  make(map[string][]float64),
+		inputs: make([]float32, enc.Size()),
+		mask:   make([]float32, enc.NumActions()),
+	}
+	return p
+}
+
+// Probs returns the strategy of the network at the infoset of game, which is uniformly random if the network fails.
+func (p *Policy) Probs(game tree.Game) []float64 {
+	infoset := game.Infoset()
+	if probs, ok := p.probs[infoset]; ok {
+		return probs
+	}
+
+	p.enc.Encode(game, p.inputs)
+	p.enc.Mask(game, p.mask)
+	probs := make([]float64, game.ActionsLen())
+	out, err := p.net.Predict([][]float32{p.inputs}, [][]float32{p.mask})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		for i := range probs {
+			probs[i] = 1 / float64(len(probs))
+		}
+		return probs
+	}
+
+	// Renormalize over the legal actions, in case the network leaks probability to the masked slots.
+	slots := make([]int, game.ActionsLen())
+	p.enc.Actions(game, slots)
+	var sum float64
+	for i, s := range slots {
+		probs[i] = float64(out[0][s])
+		sum += probs[i]
+	}
+	for i := range probs {
+		if sum > 0 {
+			probs[i] /= sum
+		} else {
+			probs[i] = 1 / float64(len(probs))
+		}
+	}
+	p.probs[infoset] = probs
+	return probs
+}
@@ -0,0 +1,540 @@
+// Package dudo implements the dice game Dudo.
+package dudo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+const (
+	invalidDice uint8 = 255
+)
+
+// http://mlanctot.info/files/675proj/report.pdf
+
+// http://cs.gettysburg.edu/~tneller/games/rules/dudo.pdf
+func strength(n, r, diceFaces, totalNumDices int) int {
+	if r != 1 {
+		return (diceFaces-1)*n + (n / 2) - (diceFaces - r) - 1
+	}
+
+	if n <= (totalNumDices / 2) {
+		return (2 * diceFaces * n) - n - diceFaces
+	}
+	if n == (totalNumDices/2 + 1) {
+		return (diceFaces-1)*totalNumDices + n - 1
+	}
+	panic(fmt.Sprintf("with r == 1, n %d cannot be larger than %d", n, totalNumDices/2+1))
+}
+
+type Claim struct {
+	Num  uint8
+	Rank uint8
+}
+
+type Dudo struct {
+	// Recall is the number of most recent claims remembered in infosets.
+	// Zero means perfect recall of all claims.
+	// With imperfect recall, the remembered claims are packed into a bitset,
+	// which is possible because claims are strictly increasing.
+	Recall int
+
+	rules  Rules
+	claims []Claim
+
+	// history holds action ids, which are claim ids followed by DudoAction or CalzaAction.
+	history []uint16
+	dices   [][]uint8
+}
+
+func NewDudo(diceFaces uint8, numDices []uint8) Dudo {
+	dudo, err := NewDudoRules(DefaultRules(diceFaces), numDices)
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+	return dudo
+}
+
+func NewDudoRules(rules Rules, numDices []uint8) (Dudo, error) {
+	if err := rules.Validate(); err != nil {
+		return Dudo{}, errors.Wrap(err, "rules.Validate")
+	}
+	dudo := Dudo{
+		rules:   rules,
+		history: make([]uint16, 0),
+	}
+
+	// Enumerate the claims.
+	totalNumDices := 0
+	for _, playerNumDices := range numDices {
+		totalNumDices += int(playerNumDices)
+	}
+	if totalNumDices > math.MaxUint8 {
+		return Dudo{}, errors.Errorf("total number of dices %d too large", totalNumDices)
+	}
+	claims, err := rules.enumerateClaims(totalNumDices)
+	if err != nil {
+		return Dudo{}, errors.Wrap(err, "enumerateClaims")
+	}
+	dudo.claims = claims
+	if len(dudo.claims)+2 > math.MaxUint16 {
+		return Dudo{}, errors.Errorf("number of claims %d too large for uint16 actions", len(dudo.claims))
+	}
+
+	// Initialize all players' dices.
+	numPlayers := len(numDices)
+	dudo.dices = make([][]uint8, numPlayers)
+	for p := 0; p < numPlayers; p++ {
+		dudo.dices[p] = make([]uint8, numDices[p])
+		for i := 0; i < len(dudo.dices[p]); i++ {
+			dudo.dices[p][i] = invalidDice
+		}
+	}
+
+	return dudo, nil
+}
+
+// ParseNumDices parses the number of dices of each player, such as "1,1,1" for a 3 player game.
+func ParseNumDices(s string) ([]uint8, error) {
+	numDices := make([]uint8, 0)
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, errors.Wrap(err, "strconv.Atoi")
+		}
+		if n < 1 || n > 254 {
+			return nil, errors.Errorf("invalid number of dices %d", n)
+		}
+		numDices = append(numDices, uint8(n))
+	}
+	return numDices, nil
+}
+
+func (dudo Dudo) Claims() []Claim {
+	return dudo.claims
+}
+
+func (dudo Dudo) Rules() Rules {
+	return dudo.rules
+}
+
+// DudoAction is the action that challenges the last claim.
+func (dudo Dudo) DudoAction() uint16 {
+	return uint16(len(dudo.claims))
+}
+
+// CalzaAction is the action that calls the last claim exact, if Rules.Calza is set.
+func (dudo Dudo) CalzaAction() uint16 {
+	return uint16(len(dudo.claims) + 1)
+}
+
+// ActionString returns a human readable action, such as "3x5" for three fives, "dudo" or "calza".
+func (dudo Dudo) ActionString(a uint16) string {
+	switch {
+	case a == dudo.DudoAction():
+		return "dudo"
+	case a == dudo.CalzaAction():
+		return "calza"
+	case int(a) < len(dudo.claims):
+		clm := dudo.claims[a]
+		return fmt.Sprintf("%dx%d", clm.Num, clm.Rank)
+	}
+	return fmt.Sprintf("invalid(%d)", a)
+}
+
+// Dices returns the dices of player, which are shared with dudo.
+func (dudo Dudo) Dices(player int) []uint8 {
+	return dudo.dices[player]
+}
+
+func (dudo Dudo) NumPlayers() int {
+	return len(dudo.dices)
+}
+
+func (dudo Dudo) History() []uint16 {
+	return dudo.history
+}
+
+func (dudo Dudo) CurPlayer() int {
+	numPlayers := len(dudo.dices)
+	player := len(dudo.history) % numPlayers
+	return player
+}
+
+func (dudo Dudo) InfosetLen() int {
+	playerDices := dudo.dices[dudo.CurPlayer()]
+	size := 1 // for the number of dices
+	size += len(playerDices)
+	size += 1 // for the player
+	if dudo.Recall > 0 {
+		size += dudo.claimBitsetLen()
+	} else {
+		size += 2 * len(dudo.history)
+	}
+	return size
+}
+
+// Infoset writes the infoset of the current player, which is laid out as
+// the number of her dices, her dices in sorted order, the player, and then the claims.
+// With perfect recall, the claims are the history with two bytes per action,
+// otherwise they are a bitset of the last Recall claims.
+// All fields have lengths known in advance, so there are no separators to collide with.
+func (dudo Dudo) Infoset(outInfoset []uint8) {
+	cursor := 0
+
+	player := dudo.CurPlayer()
+	playerDices := dudo.dices[player]
+	outInfoset[cursor] = uint8(len(playerDices))
+	cursor += 1
+	copy(outInfoset[cursor:], playerDices)
+	cursor += len(playerDices)
+
+	outInfoset[cursor] = uint8(player)
+	cursor += 1
+
+	if dudo.Recall > 0 {
+		bitset := outInfoset[cursor : cursor+dudo.claimBitsetLen()]
+		for i := range bitset {
+			bitset[i] = 0
+		}
+		first := len(dudo.history) - dudo.Recall
+		if first < 0 {
+			first = 0
+		}
+		for _, claimID := range dudo.history[first:] {
+			bitset[claimID/8] |= 1 << (claimID % 8)
+		}
+		return
+	}
+
+	for _, a := range dudo.history {
+		outInfoset[cursor] = uint8(a >> 8)
+		outInfoset[cursor+1] = uint8(a)
+		cursor += 2
+	}
+}
+
+// ParseInfoset returns the dices, the player and the remembered claims of an infoset written by Infoset.
+func (dudo Dudo) ParseInfoset(infoset string) (dices []uint8, player int, claims []uint16, err error) {
+	if len(infoset) < 1 {
+		return nil, -1, nil, errors.Errorf("empty infoset")
+	}
+	numDices := int(infoset[0])
+	if len(infoset) < 1+numDices+1 {
+		return nil, -1, nil, errors.Errorf("infoset of %d bytes too short for %d dices", len(infoset), numDices)
+	}
+	dices = []uint8(infoset[1 : 1+numDices])
+	player = int(infoset[1+numDices])
+	rest := infoset[1+numDices+1:]
+
+	claims = make([]uint16, 0)
+	if dudo.Recall > 0 {
+		if len(rest) != dudo.claimBitsetLen() {
+			return nil, -1, nil, errors.Errorf("claim bitset has %d bytes, expected %d", len(rest), dudo.claimBitsetLen())
+		}
+		for c := 0; c < len(dudo.claims); c++ {
+			if rest[c/8]&(1<<(c%8)) != 0 {
+				claims = append(claims, uint16(c))
+			}
+		}
+		return dices, player, claims, nil
+	}
+
+	if len(rest)%2 != 0 {
+		return nil, -1, nil, errors.Errorf("odd history length %d", len(rest))
+	}
+	for i := 0; i < len(rest); i += 2 {
+		claims = append(claims, uint16(rest[i])<<8|uint16(rest[i+1]))
+	}
+	return dices, player, claims, nil
+}
+
+// FormatInfoset returns a human readable infoset, such as "35|1x2,2x6".
+// With imperfect recall, the player is not implied by the claims, and is appended such as "35|1x2,2x6|p1".
+func (dudo Dudo) FormatInfoset(infoset string) string {
+	dices, player, claims, err := dudo.ParseInfoset(infoset)
+	if err != nil {
+		return fmt.Sprintf("%q", infoset)
+	}
+
+	diceStrs := make([]string, 0, len(dices))
+	for _, d := range dices {
+		diceStrs = append(diceStrs, strconv.Itoa(int(d)))
+	}
+	claimStrs := make([]string, 0, len(claims))
+	for _, c := range claims {
+		claimStrs = append(claimStrs, dudo.ActionString(c))
+	}
+	formatted := strings.Join(diceStrs, "") + "|" + strings.Join(claimStrs, ",")
+	if dudo.Recall > 0 {
+		formatted += fmt.Sprintf("|p%d", player)
+	}
+	return formatted
+}
+
+func (dudo Dudo) claimBitsetLen() int {
+	return (len(dudo.claims) + 7) / 8
+}
+
+func (dudo Dudo) IsTerminal() bool {
+	if len(dudo.history) == 0 {
+		return false
+	}
+	lastAct := dudo.history[len(dudo.history)-1]
+	return int(lastAct) >= len(dudo.claims)
+}
+
+func (dudo Dudo) Payoff(outPayoff []float64) {
+	// Find the player who challenged Dudo, or called Calza.
+	numPlayers := len(dudo.dices)
+	lastIdx := len(dudo.history) - 1
+	lastPlayer := lastIdx % numPlayers
+
+	// Find the player whose claim was challenged.
+	claimIdx := len(dudo.history) - 2
+	claimPlayer := claimIdx % numPlayers
+	claimID := dudo.history[claimIdx]
+
+	if dudo.history[lastIdx] == dudo.CalzaAction() {
+		dudo.CalzaPayoff(lastPlayer, claimID, outPayoff)
+		return
+	}
+	dudo.ChallengePayoff(claimPlayer, lastPlayer, claimID, outPayoff)
+}
+
+// count returns the actual total number of dices that have rank.
+func (dudo Dudo) count(rank uint8) int {
+	wild := dudo.rules.wild()
+	actual := 0
+	for _, playerDices := range dudo.dices {
+		for _, d := range playerDices {
+			if d == rank || (wild && d == 1) {
+				actual++
+			}
+		}
+	}
+	return actual
+}
+
+// ClaimProb returns the probability that claim is true given the dices of player,
+// with the dices of the other players rolled uniformly at random.
+// A claim is true if there are at least claim.Num dices of its rank, or exactly claim.Num if exact, as called by calza.
+func (dudo Dudo) ClaimProb(player int, claim Claim, exact bool) float64 {
+	wild := dudo.rules.wild()
+	own := 0
+	for _, d := range dudo.dices[player] {
+		if d == claim.Rank || (wild && d == 1) {
+			own++
+		}
+	}
+	unknown := 0
+	for p, playerDices := range dudo.dices {
+		if p != player {
+			unknown += len(playerDices)
+		}
+	}
+	// q is the probability that an unknown dice counts towards the claim.
+	q := 1 / float64(dudo.rules.DiceFaces)
+	if wild && claim.Rank != 1 {
+		q *= 2
+	}
+
+	// The unknown dices that count towards the claim are binomially distributed.
+	var prob float64
+	binom := 1.0 // C(unknown, k)
+	for k := 0; k <= unknown; k++ {
+		if k > 0 {
+			binom = binom * float64(unknown-k+1) / float64(k)
+		}
+		total := own + k
+		if total == int(claim.Num) || (!exact && total > int(claim.Num)) {
+			prob += binom * math.Pow(q, float64(k)) * math.Pow(1-q, float64(unknown-k))
+		}
+	}
+	return prob
+}
+
+// CalzaPayoff writes the payoffs when calzaPlayer calls the claim claimID exact.
+// The caller gains a dice if the claim is exact, and loses one otherwise.
+// Unlike challenges, calza is not zero sum, as the dice comes from or goes to the pool.
+func (dudo Dudo) CalzaPayoff(calzaPlayer int, claimID uint16, outPayoff []float64) {
+	for p := range dudo.dices {
+		outPayoff[p] = 0
+	}
+	claim := dudo.claims[claimID]
+	if dudo.count(claim.Rank) == int(claim.Num) {
+		outPayoff[calzaPlayer] = 1
+	} else {
+		outPayoff[calzaPlayer] = -1
+	}
+}
+
+// ChallengePayoff writes the payoffs when dudoPlayer challenges the claim claimID of claimPlayer.
+// A negative payoff is the number of dices the player loses.
+//
+// If the claim is exact, every player other than the claimant loses one dice to her.
+// Otherwise, the loser of the challenge loses the difference between the claim and the actual count to the winner,
+// and the other players are bystanders whose payoffs are zero.
+// Payoffs therefore always sum to zero.
+func (dudo Dudo) ChallengePayoff(claimPlayer, dudoPlayer int, claimID uint16, outPayoff []float64) {
+	numPlayers := len(dudo.dices)
+	claim := dudo.claims[claimID]
+
+	// Count the actual total number of dices that have the claimed rank.
+	actual := dudo.count(claim.Rank)
+
+	// If actual rank count is equal to claim,
+	// the player who makes the claim wins, and everyone else pays her one dice.
+	if actual == int(claim.Num) {
+		for p := 0; p < numPlayers; p++ {
+			if p == claimPlayer {
+				outPayoff[p] = float64(numPlayers) - 1
+			} else {
+				outPayoff[p] = -1
+			}
+		}
+		return
+	}
+
+	for p := 0; p < numPlayers; p++ {
+		outPayoff[p] = 0
+	}
+	outPayoff[claimPlayer] = float64(actual - int(claim.Num))
+	outPayoff[dudoPlayer] = float64(int(claim.Num) - actual)
+}
+
+// IsChanceNode reports whether the dices are yet to be rolled.
+// All players roll at the start of the game,
+// so that the dices of players who never get to act still count in challenges.
+func (dudo Dudo) IsChanceNode() bool {
+	firstDice := 0
+	for _, playerDices := range dudo.dices {
+		if len(playerDices) > 0 && playerDices[firstDice] == invalidDice {
+			return true
+		}
+	}
+	return false
+}
+
+// SampleChance rolls the dices of each player in turn, with an outcome of src for each roll.
+func (dudo Dudo) SampleChance(src chance.Source) error {
+	for p := range dudo.dices {
+		if _, err := dudo.SampleRoll(p, src); err != nil {
+			return errors.Wrapf(err, "player %d", p)
+		}
+	}
+	return nil
+}
+
+// ChanceLen returns the number of outcomes of the chance node,
+// which are all the sorted rolls of all players' dices.
+func (dudo Dudo) ChanceLen() int {
+	size := 1
+	for p := range dudo.dices {
+		size *= dudo.RollLen(p)
+	}
+	return size
+}
+
+// Chance rolls all players' dices to the outcome-th roll in [0, ChanceLen()).
+// Like SampleChance, it writes to the dices shared by all copies of dudo.
+func (dudo Dudo) Chance(outcome int) {
+	for p := range dudo.dices {
+		rollLen := dudo.RollLen(p)
+		dudo.Roll(p, outcome%rollLen)
+		outcome /= rollLen
+	}
+}
+
+// ChanceProb returns the probability of the outcome-th roll of all players.
+func (dudo Dudo) ChanceProb(outcome int) float64 {
+	var prob float64 = 1
+	for p := range dudo.dices {
+		rollLen := dudo.RollLen(p)
+		prob *= dudo.RollProb(p, outcome%rollLen)
+		outcome /= rollLen
+	}
+	return prob
+}
+
+func (dudo Dudo) ActionsLen() int {
+	if len(dudo.history) == 0 {
+		return len(dudo.claims)
+	}
+
+	size := 0
+	lastClaim := int(dudo.history[len(dudo.history)-1])
+	for c := lastClaim + 1; c < len(dudo.claims); c++ {
+		if dudo.claimAllowed(c) {
+			size++
+		}
+	}
+	size += 1 // for dudo
+	if dudo.rules.Calza {
+		size += 1
+	}
+	return size
+}
+
+// Actions writes the allowed claims in increasing strength,
+// followed by DudoAction and CalzaAction if there is a claim to respond to.
+func (dudo Dudo) Actions(outActions []uint16) {
+	if len(dudo.history) == 0 {
+		for i := 0; i < len(outActions); i++ {
+			outActions[i] = uint16(i)
+		}
+		return
+	}
+
+	cursor := 0
+	lastClaim := int(dudo.history[len(dudo.history)-1])
+	for c := lastClaim + 1; c < len(dudo.claims); c++ {
+		if dudo.claimAllowed(c) {
+			outActions[cursor] = uint16(c)
+			cursor++
+		}
+	}
+	outActions[cursor] = dudo.DudoAction()
+	cursor++
+	if dudo.rules.Calza {
+		outActions[cursor] = dudo.CalzaAction()
+	}
+}
+
+// claimAllowed reports whether claim c may follow the claims in the history.
+func (dudo Dudo) claimAllowed(c int) bool {
+	if !dudo.rules.Palifico || len(dudo.history) == 0 {
+		return true
+	}
+	// In palifico rounds, the rank is locked by the first claim.
+	firstClaim := dudo.claims[dudo.history[0]]
+	return dudo.claims[c].Rank == firstClaim.Rank
+}
+
+// Play returns the state after the current player takes action a.
+// The returned state shares its history with dudo,
+// so only one child of dudo is valid at a time unless dudo is a Clone.
+func (dudo Dudo) Play(a uint16) Dudo {
+	dudo.history = append(dudo.history, a)
+	return dudo
+}
+
+// Clone returns a copy of dudo that shares no history or dices with it.
+func (dudo Dudo) Clone() Dudo {
+	history := make([]uint16, len(dudo.history))
+	copy(history, dudo.history)
+	dudo.history = history
+
+	dices := make([][]uint8, len(dudo.dices))
+	for p, playerDices := range dudo.dices {
+		dices[p] = make([]uint8, len(playerDices))
+		copy(dices[p], playerDices)
+	}
+	dudo.dices = dices
+	return dudo
+}
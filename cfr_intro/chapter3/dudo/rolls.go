@@ -0,0 +1,103 @@
+package dudo
+
+import (
+	"math"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+// Rolls are canonicalised to sorted multisets, since the order of a player's dices carries no information.
+// Multisets of numDices dices are ranked in lexicographic order,
+// so that roll indices are contiguous without enumerating or storing the multisets.
+
+// multichoose returns the number of multisets of size k with elements from n kinds.
+func multichoose(n, k int) int {
+	// C(n+k-1, k)
+	c := 1
+	for i := 1; i <= k; i++ {
+		c = c * (n + i - 1) / i
+	}
+	return c
+}
+
+// numMultisets returns the number of sorted rolls of numDices dices.
+func numMultisets(diceFaces, numDices int) int {
+	return multichoose(diceFaces, numDices)
+}
+
+// unrankMultiset writes the rank-th sorted roll to outDices.
+func unrankMultiset(diceFaces, rank int, outDices []uint8) {
+	lo := 1
+	for i := range outDices {
+		remaining := len(outDices) - i - 1
+		for v := lo; v <= diceFaces; v++ {
+			// The number of rolls whose i-th dice is v.
+			count := multichoose(diceFaces-v+1, remaining)
+			if rank < count {
+				outDices[i] = uint8(v)
+				lo = v
+				break
+			}
+			rank -= count
+		}
+	}
+}
+
+// multisetProb returns the probability of rolling the sorted roll dices, which is the multinomial
+// numDices! / (c1! c2! ... cf!) / diceFaces^numDices, where ci is the number of dices of rank i.
+func multisetProb(diceFaces int, dices []uint8) float64 {
+	var prob float64 = 1
+	n, run := 0, 0
+	for i, d := range dices {
+		if i > 0 && d == dices[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		n++
+		prob *= float64(n) / float64(run)
+	}
+	return prob / math.Pow(float64(diceFaces), float64(len(dices)))
+}
+
+// RollLen returns the number of sorted rolls of a player's dices.
+func (dudo Dudo) RollLen(player int) int {
+	return numMultisets(int(dudo.rules.DiceFaces), len(dudo.dices[player]))
+}
+
+// Roll sets a player's dices to the roll-th sorted roll in [0, RollLen(player)).
+func (dudo Dudo) Roll(player, roll int) {
+	unrankMultiset(int(dudo.rules.DiceFaces), roll, dudo.dices[player])
+}
+
+// RollProb returns the probability of a player's roll-th sorted roll.
+func (dudo Dudo) RollProb(player, roll int) float64 {
+	dices := make([]uint8, len(dudo.dices[player]))
+	unrankMultiset(int(dudo.rules.DiceFaces), roll, dices)
+	return multisetProb(int(dudo.rules.DiceFaces), dices)
+}
+
+// rollNode is the chance node of the roll of a player's dices, whose outcomes are the sorted rolls.
+type rollNode struct {
+	dudo   Dudo
+	player int
+}
+
+func (n rollNode) ChanceLen() int {
+	return n.dudo.RollLen(n.player)
+}
+
+func (n rollNode) ChanceProb(roll int) float64 {
+	return n.dudo.RollProb(n.player, roll)
+}
+
+// SampleRoll rolls a player's dices with an outcome of src, and returns the index of the sorted roll.
+func (dudo Dudo) SampleRoll(player int, src chance.Source) (int, error) {
+	roll, err := src.Outcome(rollNode{dudo: dudo, player: player})
+	if err != nil {
+		return -1, errors.Wrap(err, "Outcome")
+	}
+	dudo.Roll(player, roll)
+	return roll, nil
+}
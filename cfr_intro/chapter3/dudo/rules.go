@@ -0,0 +1,121 @@
+package dudo
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Ordering is a rule for which claims are stronger than others.
+type Ordering int
+
+const (
+	// NellerOrdering is the ordering of http://cs.gettysburg.edu/~tneller/games/rules/dudo.pdf,
+	// where n wild ones rank just above 2n-1 of any other rank.
+	NellerOrdering Ordering = iota
+	// CountOrdering ranks claims by the number of dices, and then by the rank,
+	// treating ones as an ordinary rank.
+	CountOrdering
+)
+
+func ParseOrdering(s string) (Ordering, error) {
+	switch s {
+	case "neller":
+		return NellerOrdering, nil
+	case "count":
+		return CountOrdering, nil
+	}
+	return 0, errors.Errorf("unknown ordering %q", s)
+}
+
+// Rules are the rule variants of Dudo and Perudo.
+type Rules struct {
+	DiceFaces uint8
+	// WildOnes counts ones as dices of every rank.
+	WildOnes bool
+	// Palifico is a round without wilds, in which every claim must have the rank of the first claim.
+	Palifico bool
+	// Calza allows players to call the last claim exact instead of challenging it.
+	// If the claim is exact, the caller gains a dice, otherwise she loses one.
+	Calza    bool
+	Ordering Ordering
+}
+
+// DefaultRules are the rules of http://cs.gettysburg.edu/~tneller/games/rules/dudo.pdf.
+func DefaultRules(diceFaces uint8) Rules {
+	rules := Rules{
+		DiceFaces: diceFaces,
+		WildOnes:  true,
+		Ordering:  NellerOrdering,
+	}
+	return rules
+}
+
+func (rules Rules) Validate() error {
+	if rules.DiceFaces < 2 {
+		return errors.Errorf("dices need at least 2 faces, got %d", rules.DiceFaces)
+	}
+	if rules.Ordering == NellerOrdering && !rules.wild() {
+		return errors.Errorf("the ordering of Neller needs wild ones")
+	}
+	return nil
+}
+
+// DefaultOrdering returns the ordering of Neller if ones are wild in this round, which it needs, and CountOrdering otherwise.
+func (rules Rules) DefaultOrdering() Ordering {
+	if rules.wild() {
+		return NellerOrdering
+	}
+	return CountOrdering
+}
+
+// wild reports whether ones are wild in this round.
+func (rules Rules) wild() bool {
+	return rules.WildOnes && !rules.Palifico
+}
+
+func (rules Rules) strength(clm Claim, totalNumDices int) int {
+	n, r := int(clm.Num), int(clm.Rank)
+	switch rules.Ordering {
+	case NellerOrdering:
+		return strength(n, r, int(rules.DiceFaces), totalNumDices)
+	case CountOrdering:
+		return (n-1)*int(rules.DiceFaces) + (r - 1)
+	}
+	panic(errors.Errorf("unknown ordering %d", rules.Ordering))
+}
+
+// enumerateClaims returns the claims ordered from the weakest to the strongest.
+// It checks that the strength of the ordering maps the claims one to one onto their indices.
+func (rules Rules) enumerateClaims(totalNumDices int) ([]Claim, error) {
+	candidates := make([]Claim, 0)
+	switch rules.Ordering {
+	case NellerOrdering:
+		for n := 1; n <= totalNumDices; n++ {
+			for r := 2; r <= int(rules.DiceFaces); r++ {
+				candidates = append(candidates, Claim{Num: uint8(n), Rank: uint8(r)})
+			}
+		}
+		for n := 1; n <= totalNumDices/2+1; n++ {
+			candidates = append(candidates, Claim{Num: uint8(n), Rank: 1})
+		}
+	case CountOrdering:
+		for n := 1; n <= totalNumDices; n++ {
+			for r := 1; r <= int(rules.DiceFaces); r++ {
+				candidates = append(candidates, Claim{Num: uint8(n), Rank: uint8(r)})
+			}
+		}
+	default:
+		return nil, errors.Errorf("unknown ordering %d", rules.Ordering)
+	}
+
+	claims := make([]Claim, len(candidates))
+	filled := make([]bool, len(candidates))
+	for _, clm := range candidates {
+		s := rules.strength(clm, totalNumDices)
+		if s < 0 || s >= len(claims) || filled[s] {
+			return nil, errors.Errorf("claim %+v has invalid strength %d among %d claims", clm, s, len(claims))
+		}
+		claims[s] = clm
+		filled[s] = true
+	}
+	return claims, nil
+}
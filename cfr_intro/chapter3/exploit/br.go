@@ -0,0 +1,216 @@
+package exploit
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// recalling is the game in which player remembers her past infosets and actions,
+// so that she has perfect recall even if the infosets of game forget some of her history, such as with dudo.Dudo.Recall.
+// Her infosets are the infosets of game, prefixed by their length, followed by her history.
+type recalling struct {
+	game    tree.Game
+	player  int
+	history string
+}
+
+func (r *recalling) NumPlayers() int {
+	return r.game.NumPlayers()
+}
+
+func (r *recalling) IsTerminal() bool {
+	return r.game.IsTerminal()
+}
+
+func (r *recalling) Payoff(outPayoff []float64) {
+	r.game.Payoff(outPayoff)
+}
+
+func (r *recalling) IsChanceNode() bool {
+	return r.game.IsChanceNode()
+}
+
+func (r *recalling) ChanceLen() int {
+	return r.game.ChanceLen()
+}
+
+func (r *recalling) ChanceProb(outcome int) float64 {
+	return r.game.ChanceProb(outcome)
+}
+
+func (r *recalling) Chance(outcome int) tree.Game {
+	child := *r
+	child.game = r.game.Chance(outcome)
+	return &child
+}
+
+func (r *recalling) CurPlayer() int {
+	return r.game.CurPlayer()
+}
+
+func (r *recalling) ActionsLen() int {
+	return r.game.ActionsLen()
+}
+
+func (r *recalling) Play(aIdx int) tree.Game {
+	child := *r
+	if r.game.CurPlayer() == r.player {
+		child.history += prefixLen(r.game.Infoset()) + strconv.Itoa(aIdx) + ";"
+	}
+	child.game = r.game.Play(aIdx)
+	return &child
+}
+
+func (r *recalling) Infoset() string {
+	if r.game.CurPlayer() != r.player {
+		return r.game.Infoset()
+	}
+	return prefixLen(r.game.Infoset()) + r.history
+}
+
+// prefixLen prefixes infoset with its length, so that it is delimited whatever bytes it contains.
+func prefixLen(infoset string) string {
+	return strconv.Itoa(len(infoset)) + ":" + infoset
+}
+
+// forget returns the infoset of the game wrapped by recalling, given an infoset of recalling.
+func forget(infoset string) (string, error) {
+	colon := strings.IndexByte(infoset, ':')
+	if colon < 0 {
+		return "", errors.Errorf("no length")
+	}
+	n, err := strconv.Atoi(infoset[:colon])
+	if err != nil {
+		return "", errors.Wrap(err, "Atoi")
+	}
+	if colon+1+n > len(infoset) {
+		return "", errors.Errorf("length %d out of range", n)
+	}
+	return infoset[colon+1 : colon+1+n], nil
+}
+
+// bestResponse returns the tree of the game in which the model is played by chance and player has perfect recall,
+// the best action at each infoset of the tree, and the expected payoff of the best response.
+func bestResponse(root tree.Game, player int, model strategy.Policy) (*tree.Tree, []int, float64) {
+	// Only player decides in the tree, as the model is played by chance.
+	t := tree.Build(&recalling{game: newRestricted(root, player, model, 1), player: player})
+
+	// reach is the probability of chance, including the model, reaching each node,
+	// and decisions is the number of decisions of player before each node.
+	reach := make([]float64, t.NumNodes())
+	decisions := make([]int, t.NumNodes())
+	reach[0] = 1
+	for n := 1; n < t.NumNodes(); n++ {
+		parent := t.Parent[n]
+		reach[n] = reach[parent] * t.ChanceProb[n]
+		decisions[n] = decisions[parent]
+		if t.Infoset[parent] >= 0 {
+			decisions[n]++
+		}
+	}
+
+	// With perfect recall, all nodes of an infoset follow the same number of decisions of player,
+	// and the infosets after them follow more.
+	// So the best action of an infoset is known once the infosets with more decisions are decided.
+	levels := make([][]int, 0)
+	nodes := make([][]int, t.NumInfosets())
+	for n := 0; n < t.NumNodes(); n++ {
+		is := int(t.Infoset[n])
+		if is < 0 {
+			continue
+		}
+		if len(nodes[is]) == 0 {
+			for len(levels) <= decisions[n] {
+				levels = append(levels, nil)
+			}
+			levels[decisions[n]] = append(levels[decisions[n]], is)
+		}
+		nodes[is] = append(nodes[is], n)
+	}
+
+	value := make([]float64, t.NumNodes())
+	valued := make([]bool, t.NumNodes())
+	best := make([]int, t.NumInfosets())
+	var valueOf func(n int) float64
+	valueOf = func(n int) float64 {
+		if valued[n] {
+			return value[n]
+		}
+		first := int(t.FirstChild[n])
+		switch int(t.Player[n]) {
+		case tree.Terminal:
+			value[n] = t.Payoff[n*t.NumPlayers+player]
+		case tree.Chance:
+			for i := 0; i < int(t.NumChildren[n]); i++ {
+				value[n] += t.ChanceProb[first+i] * valueOf(first+i)
+			}
+		default:
+			value[n] = valueOf(first + best[t.Infoset[n]])
+		}
+		valued[n] = true
+		return value[n]
+	}
+	for d := len(levels) - 1; d >= 0; d-- {
+		for _, is := range levels[d] {
+			// Sum the values of the actions over the nodes of the infoset, weighted by their reach.
+			actionValues := make([]float64, t.NumActions(is))
+			for _, n := range nodes[is] {
+				first := int(t.FirstChild[n])
+				for i := range actionValues {
+					actionValues[i] += reach[n] * valueOf(first+i)
+				}
+			}
+			for i := 1; i < len(actionValues); i++ {
+				if actionValues[i] > actionValues[best[is]] {
+					best[is] = i
+				}
+			}
+		}
+	}
+	return t, best, valueOf(0)
+}
+
+// BestResponse returns the best response of player to the model, and its expected payoff.
+// The best response is a pure strategy keyed by the infosets of player.
+// It is an error if the infosets of player forget some of her history, such as with dudo.Dudo.Recall,
+// as a best response may then play differently at the nodes of an infoset; BestResponseValue still values it.
+func BestResponse(root tree.Game, player int, model strategy.Policy) (map[string][]float64, float64, error) {
+	t, best, value := bestResponse(root, player, model)
+	strategies := make(map[string][]float64, t.NumInfosets())
+	for is, key := range t.InfosetKey {
+		infoset, err := forget(key)
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "infoset %q", key)
+		}
+		if _, ok := strategies[infoset]; ok {
+			return nil, 0, errors.Errorf("player %d does not have perfect recall at infoset %q", player, infoset)
+		}
+		s := make([]float64, t.NumActions(is))
+		s[best[is]] = 1
+		strategies[infoset] = s
+	}
+	return strategies, value, nil
+}
+
+// BestResponseValue returns the expected payoff of the best response of player to the model,
+// in which player remembers all her history even if her infosets do not.
+func BestResponseValue(root tree.Game, player int, model strategy.Policy) float64 {
+	_, _, value := bestResponse(root, player, model)
+	return value
+}
+
+// Exploitability returns the sum of how much each player gains by switching to a best response when all play policy.
+// It is zero exactly when policy is a Nash equilibrium, and is measured in the unabstracted game if policy is keyed by abstracted infosets.
+func Exploitability(root tree.Game, policy strategy.Policy) float64 {
+	values := match.Value(root, policy)
+	var sum float64
+	for player, v := range values {
+		sum += BestResponseValue(root, player, policy) - v
+	}
+	return sum
+}
@@ -0,0 +1,162 @@
+package exploit
+
+import (
+	"math"
+
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Confidence is how much a data biased response trusts the model at an infoset,
+// which grows linearly with the number of observations of the infoset up to PMax.
+type Confidence struct {
+	// PMax is the largest probability that the opponent plays the model at an infoset.
+	PMax float64
+	// Observations is the number of observations at which the confidence reaches PMax.
+	Observations float64
+}
+
+func (c Confidence) Validate() error {
+	if c.PMax < 0 || c.PMax > 1 {
+		return errors.Errorf("invalid PMax %f", c.PMax)
+	}
+	if c.Observations <= 0 {
+		return errors.Errorf("invalid number of observations %f", c.Observations)
+	}
+	return nil
+}
+
+// Prob returns the probability that the opponent plays the model at an infoset observed n times.
+func (c Confidence) Prob(n float64) float64 {
+	return c.PMax * math.Min(1, n/c.Observations)
+}
+
+const (
+	// undecided is an opponent decision before chance decides whether she plays the model.
+	undecided = iota
+	chosenModel
+	chosenFree
+)
+
+// biased is the game in which the opponent of player plays the model at each infoset with the confidence of its observations,
+// and otherwise plays as she likes.
+// Unlike restricted, whether she plays the model is decided at each of her decisions,
+// so that she is constrained only where there is data.
+type biased struct {
+	game       tree.Game
+	player     int
+	model      *Model
+	confidence Confidence
+
+	// decision is undecided, chosenModel or chosenFree at the decisions of the opponent.
+	decision int
+}
+
+// prob returns the probability that the opponent plays the model at the current node.
+func (b *biased) prob() float64 {
+	return b.confidence.Prob(b.model.Observations(b.game.Infoset()))
+}
+
+// opponentNode returns whether the current node is a decision of the opponent.
+func (b *biased) opponentNode() bool {
+	return !b.game.IsTerminal() && !b.game.IsChanceNode() && b.game.CurPlayer() != b.player
+}
+
+// next returns the state after game, deciding right away if the opponent certainly plays the model or not,
+// so that the tree is no larger than needed.
+func (b *biased) next(game tree.Game) *biased {
+	child := *b
+	child.game = game
+	child.decision = undecided
+	if child.opponentNode() {
+		switch child.prob() {
+		case 0:
+			child.decision = chosenFree
+		case 1:
+			child.decision = chosenModel
+		}
+	}
+	return &child
+}
+
+func (b *biased) NumPlayers() int {
+	return b.game.NumPlayers()
+}
+
+func (b *biased) IsTerminal() bool {
+	return b.game.IsTerminal()
+}
+
+func (b *biased) Payoff(outPayoff []float64) {
+	b.game.Payoff(outPayoff)
+}
+
+func (b *biased) IsChanceNode() bool {
+	if b.opponentNode() {
+		return b.decision != chosenFree
+	}
+	return b.game.IsChanceNode()
+}
+
+func (b *biased) ChanceLen() int {
+	if b.opponentNode() {
+		if b.decision == undecided {
+			return 2
+		}
+		return b.game.ActionsLen()
+	}
+	return b.game.ChanceLen()
+}
+
+func (b *biased) ChanceProb(outcome int) float64 {
+	if b.opponentNode() {
+		if b.decision == undecided {
+			if outcome == fixed {
+				return b.prob()
+			}
+			return 1 - b.prob()
+		}
+		return b.model.Probs(b.game)[outcome]
+	}
+	return b.game.ChanceProb(outcome)
+}
+
+func (b *biased) Chance(outcome int) tree.Game {
+	if b.opponentNode() {
+		if b.decision == undecided {
+			child := *b
+			child.decision = chosenFree
+			if outcome == fixed {
+				child.decision = chosenModel
+			}
+			return &child
+		}
+		return b.next(b.game.Play(outcome))
+	}
+	return b.next(b.game.Chance(outcome))
+}
+
+func (b *biased) CurPlayer() int {
+	return b.game.CurPlayer()
+}
+
+func (b *biased) ActionsLen() int {
+	return b.game.ActionsLen()
+}
+
+func (b *biased) Play(aIdx int) tree.Game {
+	return b.next(b.game.Play(aIdx))
+}
+
+func (b *biased) Infoset() string {
+	return b.game.Infoset()
+}
+
+// DataBiased returns the data biased response of player to the model after iterations of CFR, keyed by the infosets of player,
+// from "Data Biased Robust Counter Strategies" by Johanson and Bowling.
+// Where the model is based on few observations, the opponent is free to play her best, so the response stays close to an equilibrium,
+// whereas a restricted Nash response would trust the uniformly random play the model assumes there.
+func DataBiased(root tree.Game, player int, model *Model, confidence Confidence, iterations int) map[string][]float64 {
+	b := (&biased{player: player, model: model, confidence: confidence}).next(root)
+	return solve(b, player, iterations)
+}
@@ -0,0 +1,163 @@
+// Package exploit computes counter-strategies to a model of the opponent in two player zero-sum games such as Kuhn poker and Dudo.
+//
+// A best response earns the most against the model, but may lose badly if the model is wrong.
+// A restricted Nash response, from "Computing Robust Counter-Strategies" by Johanson, Zinkevich and Bowling,
+// is trained with CFR against an opponent who plays the model with probability p and her best strategy otherwise.
+// It is a Nash equilibrium strategy at p = 0 and a best response at p = 1,
+// and in between trades a little exploitation for a large reduction in exploitability.
+package exploit
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+)
+
+const (
+	// fixed is the chance outcome in which the opponent plays the model.
+	fixed = 0
+	// free is the chance outcome in which the opponent plays as she likes.
+	free = 1
+)
+
+// restricted is the game in which the opponent of player secretly plays the model with probability p.
+// Where the opponent plays the model, her decisions are chance nodes.
+type restricted struct {
+	game   tree.Game
+	player int
+	model  strategy.Policy
+	p      float64
+
+	// dealt is whether the chance of the opponent playing the model has been decided.
+	dealt bool
+	fixed bool
+}
+
+func newRestricted(root tree.Game, player int, model strategy.Policy, p float64) *restricted {
+	r := &restricted{game: root, player: player, model: model, p: p}
+	// Skip the decision if the opponent always or never plays the model, so that the tree is no larger than needed.
+	switch p {
+	case 0:
+		r.dealt = true
+	case 1:
+		r.dealt, r.fixed = true, true
+	}
+	return r
+}
+
+// modelled returns whether the current node is a decision of the opponent playing the model.
+func (r *restricted) modelled() bool {
+	return r.fixed && !r.game.IsTerminal() && !r.game.IsChanceNode() && r.game.CurPlayer() != r.player
+}
+
+func (r *restricted) NumPlayers() int {
+	return r.game.NumPlayers()
+}
+
+func (r *restricted) IsTerminal() bool {
+	return r.dealt && r.game.IsTerminal()
+}
+
+func (r *restricted) Payoff(outPayoff []float64) {
+	r.game.Payoff(outPayoff)
+}
+
+func (r *restricted) IsChanceNode() bool {
+	return !r.dealt || r.game.IsChanceNode() || r.modelled()
+}
+
+func (r *restricted) ChanceLen() int {
+	switch {
+	case !r.dealt:
+		return 2
+	case r.modelled():
+		return r.game.ActionsLen()
+	}
+	return r.game.ChanceLen()
+}
+
+func (r *restricted) ChanceProb(outcome int) float64 {
+	switch {
+	case !r.dealt:
+		if outcome == fixed {
+			return r.p
+		}
+		return 1 - r.p
+	case r.modelled():
+		return r.model.Probs(r.game)[outcome]
+	}
+	return r.game.ChanceProb(outcome)
+}
+
+func (r *restricted) Chance(outcome int) tree.Game {
+	child := *r
+	switch {
+	case !r.dealt:
+		child.dealt, child.fixed = true, outcome == fixed
+	case r.modelled():
+		child.game = r.game.Play(outcome)
+	default:
+		child.game = r.game.Chance(outcome)
+	}
+	return &child
+}
+
+func (r *restricted) CurPlayer() int {
+	return r.game.CurPlayer()
+}
+
+func (r *restricted) ActionsLen() int {
+	return r.game.ActionsLen()
+}
+
+func (r *restricted) Play(aIdx int) tree.Game {
+	child := *r
+	child.game = r.game.Play(aIdx)
+	return &child
+}
+
+func (r *restricted) Infoset() string {
+	return r.game.Infoset()
+}
+
+// RestrictedNash returns the restricted Nash response of player to the model after iterations of CFR,
+// keyed by the infosets of player.
+func RestrictedNash(root tree.Game, player int, model strategy.Policy, p float64, iterations int) map[string][]float64 {
+	return solve(newRestricted(root, player, model, p), player, iterations)
+}
+
+// solve runs iterations of CFR on game, and returns the average strategies of player.
+func solve(game tree.Game, player int, iterations int) map[string][]float64 {
+	t := tree.Build(game)
+	c := tree.NewCFR(t)
+	for i := 0; i < iterations; i++ {
+		c.Iterate()
+	}
+
+	strategies := make(map[string][]float64)
+	for is, key := range t.InfosetKey {
+		if int(t.InfosetPlayer[is]) == player {
+			strategies[key] = c.AvgStrategy(is)
+		}
+	}
+	return strategies
+}
+
+// profile plays policy as player and model as the other players.
+type profile struct {
+	player int
+	policy strategy.Policy
+	model  strategy.Policy
+}
+
+func (pf profile) Probs(game tree.Game) []float64 {
+	if game.CurPlayer() == pf.player {
+		return pf.policy.Probs(game)
+	}
+	return pf.model.Probs(game)
+}
+
+// Value returns the expected payoff of player playing policy against the model.
+func Value(root tree.Game, player int, policy, model strategy.Policy) float64 {
+	return match.Value(root, profile{player: player, policy: policy, model: model})[player]
+}
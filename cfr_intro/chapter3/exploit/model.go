@@ -0,0 +1,70 @@
+package exploit
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Model models an opponent by the frequencies of her actions at each of her infosets in logged hands.
+type Model struct {
+	root tree.Game
+	// Counts are the number of times each action is observed, keyed by infoset.
+	Counts map[string][]float64
+}
+
+func NewModel(root tree.Game) *Model {
+	m := &Model{root: root, Counts: make(map[string][]float64)}
+	return m
+}
+
+// Observe counts the actions of player in h.
+// Hands in which player did not play are ignored.
+func (m *Model) Observe(h match.Hand, player string) error {
+	position := h.Position(player)
+	if position < 0 {
+		return nil
+	}
+	states, err := h.Replay(m.root)
+	if err != nil {
+		return errors.Wrap(err, "Replay")
+	}
+	for i, aIdx := range h.Steps {
+		game := states[i]
+		if game.IsChanceNode() || game.CurPlayer() != position {
+			continue
+		}
+		counts, ok := m.Counts[game.Infoset()]
+		if !ok {
+			counts = make([]float64, game.ActionsLen())
+			m.Counts[game.Infoset()] = counts
+		}
+		counts[aIdx]++
+	}
+	return nil
+}
+
+// Observations returns the number of times the infoset is observed.
+func (m *Model) Observations(infoset string) float64 {
+	var n float64
+	for _, c := range m.Counts[infoset] {
+		n += c
+	}
+	return n
+}
+
+// Probs returns the observed frequencies of the actions at the infoset of game,
+// which are uniformly random if the infoset is never observed.
+func (m *Model) Probs(game tree.Game) []float64 {
+	numActions := game.ActionsLen()
+	probs := make([]float64, numActions)
+	n := m.Observations(game.Infoset())
+	for i := range probs {
+		if n == 0 {
+			probs[i] = 1 / float64(numActions)
+		} else {
+			probs[i] = m.Counts[game.Infoset()][i] / n
+		}
+	}
+	return probs
+}
@@ -0,0 +1,163 @@
+// Package features encodes the infosets of Kuhn poker, Dudo and matrix games as fixed-size feature vectors,
+// and their actions into a fixed action space, so that they can be fed to neural networks.
+//
+// Features only use what the current player sees, so that two states of an infoset have the same features:
+//
+//   - Kuhn: a one-hot card, and a one-hot action at each position of the history.
+//   - Dudo: the fraction of the dices of the player showing each face, a one-hot player,
+//     a bitset of the claims in the infoset, and a one-hot last claim.
+//   - Matrix: a one-hot player.
+//
+// Action slots are the actions themselves, such as kuhn.Bet or a Dudo claim id,
+// so that a slot means the same action at every infoset.
+package features
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+type Encoder struct {
+	root       tree.Game
+	size       int
+	numActions int
+	// maxHistory is the longest Kuhn history.
+	maxHistory int
+}
+
+// NewEncoder returns an encoder of the infosets of the Kuhn, Dudo or matrix game of root.
+func NewEncoder(root tree.Game) (*Encoder, error) {
+	e := &Encoder{root: root}
+	switch g := root.(type) {
+	case games.Kuhn:
+		cfg := g.State.Config()
+		e.numActions = 2
+		if cfg.MaxBets > 1 {
+			e.numActions = 3
+		}
+		e.maxHistory = maxHistory(root)
+		e.size = cfg.NumCards + e.maxHistory*e.numActions
+	case games.Dudo:
+		numClaims := len(g.State.Claims())
+		e.numActions = numClaims + 1
+		if g.State.Rules().Calza {
+			e.numActions++
+		}
+		e.size = int(g.State.Rules().DiceFaces) + g.NumPlayers() + 2*numClaims
+	case games.Matrix:
+		cfg := g.State.Config()
+		e.numActions = len(cfg.Payoff)
+		if len(cfg.Payoff[0]) > e.numActions {
+			e.numActions = len(cfg.Payoff[0])
+		}
+		e.size = 2
+	default:
+		return nil, errors.Errorf("unsupported game %T", root)
+	}
+	return e, nil
+}
+
+// maxHistory returns the largest number of actions in a game of root.
+func maxHistory(game tree.Game) int {
+	if game.IsTerminal() {
+		return 0
+	}
+	if game.IsChanceNode() {
+		// Deals do not change the betting.
+		return maxHistory(game.Chance(0))
+	}
+	longest := 0
+	for a := 0; a < game.ActionsLen(); a++ {
+		if n := maxHistory(game.Play(a)); n > longest {
+			longest = n
+		}
+	}
+	return longest + 1
+}
+
+// Size returns the number of features.
+func (e *Encoder) Size() int {
+	return e.size
+}
+
+// NumActions returns the number of action slots.
+func (e *Encoder) NumActions() int {
+	return e.numActions
+}
+
+// Encode writes the features of the infoset of the current player of game to out, which has Size elements.
+func (e *Encoder) Encode(game tree.Game, out []float32) {
+	e.EncodePlayer(game, game.CurPlayer(), out)
+}
+
+// EncodePlayer writes the features of what player sees at the non-terminal state game to out, which has Size elements.
+func (e *Encoder) EncodePlayer(game tree.Game, player int, out []float32) {
+	for i := range out {
+		out[i] = 0
+	}
+	switch g := game.(type) {
+	case games.Kuhn:
+		out[g.State.Card(player)-1] = 1
+		history := out[g.State.Config().NumCards:]
+		for i, a := range g.State.History() {
+			history[i*e.numActions+int(a)] = 1
+		}
+	case games.Dudo:
+		faces := int(g.State.Rules().DiceFaces)
+		dices := g.State.Dices(player)
+		for _, d := range dices {
+			out[d-1] += 1 / float32(len(dices))
+		}
+		out[faces+player] = 1
+
+		numClaims := len(g.State.Claims())
+		claims := out[faces+g.NumPlayers():]
+		history := g.State.History()
+		if g.State.Recall > 0 && len(history) > g.State.Recall {
+			history = history[len(history)-g.State.Recall:]
+		}
+		for _, c := range history {
+			claims[c] = 1
+		}
+		if len(history) > 0 {
+			claims[numClaims+int(history[len(history)-1])] = 1
+		}
+	case games.Matrix:
+		out[player] = 1
+	}
+}
+
+// Actions writes the slot of each action of game to out, which has game.ActionsLen() elements.
+func (e *Encoder) Actions(game tree.Game, out []int) {
+	switch g := game.(type) {
+	case games.Kuhn:
+		actions := make([]uint8, g.ActionsLen())
+		g.Actions(actions)
+		for i, a := range actions {
+			out[i] = int(a)
+		}
+	case games.Dudo:
+		actions := make([]uint16, g.ActionsLen())
+		g.Actions(actions)
+		for i, a := range actions {
+			out[i] = int(a)
+		}
+	case games.Matrix:
+		for i := range out {
+			out[i] = i
+		}
+	}
+}
+
+// Mask writes 1 to the slots of the actions of game and 0 elsewhere to out, which has NumActions elements.
+func (e *Encoder) Mask(game tree.Game, out []float32) {
+	for i := range out {
+		out[i] = 0
+	}
+	slots := make([]int, game.ActionsLen())
+	e.Actions(game, slots)
+	for _, s := range slots {
+		out[s] = 1
+	}
+}
@@ -0,0 +1,306 @@
+// Package fsicfr solves full 2 player Dudo games, in which the loser of each round loses dices until someone has none.
+//
+// Each round is abstracted to remember only the last few claims, so that its states form a small DAG,
+// which is trained with fixed-strategy iteration CFR (FSICFR).
+// Rounds are chained by using the win probabilities of the states with fewer dices as utilities.
+// This follows "Approximating Optimal Dudo Play with Fixed-Strategy Iteration Counterfactual Regret Minimization"
+// by Todd W. Neller and Steven Hnath.
+package fsicfr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/pkg/errors"
+)
+
+type node struct {
+	player int
+	// claims are the remembered claims, the last of which is the most recent.
+	claims   []uint16
+	actions  []uint16
+	children []*node // nil for the dudo action
+
+	reach    [2]float64
+	val      [2]float64
+	strategy []float64
+	// regretSum and strategySum are keyed by the roll of player.
+	regretSum   map[int][]float64
+	strategySum map[int][]float64
+	// curRegretSum and curStrategySum are those of the roll in the current iteration.
+	curRegretSum   []float64
+	curStrategySum []float64
+}
+
+func (nd *node) lastClaim() int {
+	if len(nd.claims) == 0 {
+		return -1
+	}
+	return int(nd.claims[len(nd.claims)-1])
+}
+
+func (nd *node) getStrategy(roll int) []float64 {
+	regretSum, ok := nd.regretSum[roll]
+	if !ok {
+		regretSum = make([]float64, len(nd.actions))
+		nd.regretSum[roll] = regretSum
+		nd.strategySum[roll] = make([]float64, len(nd.actions))
+	}
+	nd.curRegretSum = regretSum
+	nd.curStrategySum = nd.strategySum[roll]
+
+	var z float64 = 0
+	for _, r := range regretSum {
+		if r < 0 {
+			continue
+		}
+		z += r
+	}
+	for i, r := range regretSum {
+		if z == 0 {
+			nd.strategy[i] = 1 / float64(len(nd.actions))
+		} else if r < 0 {
+			nd.strategy[i] = 0
+		} else {
+			nd.strategy[i] = r / z
+		}
+	}
+	return nd.strategy
+}
+
+func (nd *node) avgStrategy(roll int) []float64 {
+	strategySum := nd.strategySum[roll]
+	var z float64 = 0
+	for _, s := range strategySum {
+		z += s
+	}
+	for i := range nd.strategy {
+		if z == 0 {
+			nd.strategy[i] = 1 / float64(len(nd.actions))
+		} else {
+			nd.strategy[i] = strategySum[i] / z
+		}
+	}
+	return nd.strategy
+}
+
+// Round is one round of 2 player Dudo.
+type Round struct {
+	game    dudo.Dudo
+	utility func(lost [2]int) [2]float64
+	// nodes are in topological order.
+	nodes []*node
+	rolls [2]int
+	// chance rolls the dices.
+	chance chance.Source
+}
+
+// NewRound creates a round where the players have numDices, and remember only the last recall claims.
+// utility maps the number of dices each player loses to the players' utilities, and src rolls the dices.
+func NewRound(diceFaces uint8, numDices [2]uint8, recall int, utility func(lost [2]int) [2]float64, src chance.Source) *Round {
+	if recall < 1 {
+		panic(fmt.Sprintf("recall %d must be positive", recall))
+	}
+
+	r := &Round{
+		game:    dudo.NewDudo(diceFaces, numDices[:]),
+		utility: utility,
+		chance:  src,
+	}
+
+	// Expand the DAG of remembered claims.
+	nodeMap := make(map[string]*node)
+	var getNode func(player int, claims []uint16) *node
+	getNode = func(player int, claims []uint16) *node {
+		if len(claims) > recall {
+			claims = claims[len(claims)-recall:]
+		}
+		key := fmt.Sprint(player, claims)
+		if nd, ok := nodeMap[key]; ok {
+			return nd
+		}
+
+		nd := &node{
+			player:      player,
+			claims:      claims,
+			regretSum:   make(map[int][]float64),
+			strategySum: make(map[int][]float64),
+		}
+		nodeMap[key] = nd
+		r.nodes = append(r.nodes, nd)
+
+		// Only the last claim matters for the allowed actions.
+		game := r.game.Clone()
+		for _, c := range claims {
+			game = game.Play(c)
+		}
+		nd.actions = make([]uint16, game.ActionsLen())
+		game.Actions(nd.actions)
+		nd.strategy = make([]float64, len(nd.actions))
+		nd.children = make([]*node, len(nd.actions))
+		for aIdx, a := range nd.actions {
+			if a == r.game.DudoAction() {
+				continue
+			}
+			stClaims := make([]uint16, len(claims)+1)
+			copy(stClaims, claims)
+			stClaims[len(claims)] = a
+			nd.children[aIdx] = getNode(1-player, stClaims)
+		}
+		return nd
+	}
+	getNode(0, []uint16{})
+
+	// Claims are strictly increasing, so ordering by the last claim is topological.
+	sort.SliceStable(r.nodes, func(i, j int) bool {
+		return r.nodes[i].lastClaim() < r.nodes[j].lastClaim()
+	})
+	return r
+}
+
+func (r *Round) NumNodes() int {
+	return len(r.nodes)
+}
+
+func (r *Round) sampleRolls() error {
+	for p := range r.rolls {
+		roll, err := r.game.SampleRoll(p, r.chance)
+		if err != nil {
+			return errors.Wrapf(err, "player %d", p)
+		}
+		r.rolls[p] = roll
+	}
+	return nil
+}
+
+// terminalUtility returns the utilities when the player of nd challenges the last claim.
+func (r *Round) terminalUtility(nd *node) [2]float64 {
+	var payoff [2]float64
+	r.game.ChallengePayoff(1-nd.player, nd.player, uint16(nd.lastClaim()), payoff[:])
+
+	var lost [2]int
+	for p, pay := range payoff {
+		if pay < 0 {
+			lost[p] = int(-pay)
+		}
+	}
+	return r.utility(lost)
+}
+
+// Iterate samples the dices and runs one FSICFR iteration.
+// It returns the utilities of the current strategies for the sampled dices.
+func (r *Round) Iterate() ([2]float64, error) {
+	if err := r.sampleRolls(); err != nil {
+		return [2]float64{}, errors.Wrap(err, "sampleRolls")
+	}
+
+	// Forward pass: accumulate the reach probabilities of each node.
+	r.nodes[0].reach = [2]float64{1, 1}
+	for _, nd := range r.nodes {
+		player := nd.player
+		strategy := nd.getStrategy(r.rolls[player])
+		for aIdx, child := range nd.children {
+			if child == nil {
+				continue
+			}
+			child.reach[player] += nd.reach[player] * strategy[aIdx]
+			child.reach[1-player] += nd.reach[1-player]
+		}
+	}
+
+	// Backward pass: compute the values of each node and update the regrets.
+	actionVal := make([]float64, 0)
+	for i := len(r.nodes) - 1; i >= 0; i-- {
+		nd := r.nodes[i]
+		player := nd.player
+		opponent := 1 - player
+		// The strategy is not changed since the forward pass.
+		strategy := nd.strategy
+
+		actionVal = actionVal[:0]
+		nd.val = [2]float64{0, 0}
+		for aIdx, child := range nd.children {
+			childVal := r.childVal(nd, child)
+			actionVal = append(actionVal, childVal[player])
+			for p := range nd.val {
+				nd.val[p] += strategy[aIdx] * childVal[p]
+			}
+		}
+
+		for aIdx, aVal := range actionVal {
+			nd.curRegretSum[aIdx] += nd.reach[opponent] * (aVal - nd.val[player])
+			nd.curStrategySum[aIdx] += nd.reach[player] * strategy[aIdx]
+		}
+		nd.reach = [2]float64{0, 0}
+	}
+
+	return r.nodes[0].val, nil
+}
+
+func (r *Round) childVal(nd, child *node) [2]float64 {
+	if child == nil {
+		return r.terminalUtility(nd)
+	}
+	return child.val
+}
+
+// Value estimates the utilities of the average strategies by sampling dices.
+func (r *Round) Value(samples int) ([2]float64, error) {
+	var sum [2]float64
+	for s := 0; s < samples; s++ {
+		if err := r.sampleRolls(); err != nil {
+			return [2]float64{}, errors.Wrapf(err, "sample %d", s)
+		}
+		for i := len(r.nodes) - 1; i >= 0; i-- {
+			nd := r.nodes[i]
+			strategy := nd.avgStrategy(r.rolls[nd.player])
+			nd.val = [2]float64{0, 0}
+			for aIdx, child := range nd.children {
+				childVal := r.childVal(nd, child)
+				for p := range nd.val {
+					nd.val[p] += strategy[aIdx] * childVal[p]
+				}
+			}
+		}
+
+		for p, v := range r.nodes[0].val {
+			sum[p] += v
+		}
+	}
+
+	var avg [2]float64
+	for p, s := range sum {
+		avg[p] = s / float64(samples)
+	}
+	return avg, nil
+}
+
+// RoundUtility returns the utility of a round where the player to start has dices[0] against dices[1].
+// winProb[m][o] is the probability that the player to start a round with m dices wins against o dices.
+// The utility of each player is her probability to win the game.
+//
+// The player who loses dices starts the next round,
+// so winProb needs to be known only for states with fewer dices.
+func RoundUtility(dices [2]int, winProb [][]float64) func(lost [2]int) [2]float64 {
+	return func(lost [2]int) [2]float64 {
+		var left [2]int
+		for p := range left {
+			left[p] = dices[p] - lost[p]
+		}
+
+		var p0Win float64
+		switch {
+		case left[0] <= 0:
+			p0Win = 0
+		case left[1] <= 0:
+			p0Win = 1
+		case lost[0] > 0:
+			p0Win = winProb[left[0]][left[1]]
+		default:
+			p0Win = 1 - winProb[left[1]][left[0]]
+		}
+		return [2]float64{p0Win, 1 - p0Win}
+	}
+}
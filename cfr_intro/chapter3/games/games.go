@@ -0,0 +1,22 @@
+// Package games adapts the games of this chapter, such as kuhn.Kuhn and dudo.Dudo, to tree.Game,
+// so that package tree does not depend on any particular game.
+package games
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/goofspiel"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/matrix"
+	"github.com/fumin/bangbang/cfr/chapter3/oshizumo"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+)
+
+type (
+	Kuhn      = tree.Adapter[kuhn.Kuhn, uint8]
+	Leduc     = tree.Adapter[leduc.Leduc, uint8]
+	Goofspiel = tree.Adapter[goofspiel.Goofspiel, uint8]
+	OshiZumo  = tree.Adapter[oshizumo.OshiZumo, uint8]
+	Matrix    = tree.Adapter[matrix.Matrix, uint8]
+	Dudo      = tree.Adapter[dudo.Dudo, uint16]
+)
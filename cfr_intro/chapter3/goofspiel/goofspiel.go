@@ -0,0 +1,195 @@
+// Package goofspiel implements Goofspiel with the same state interface as package kuhn.
+//
+// Each player holds the cards 1 to NumCards, and a shuffled prize deck of the same cards is revealed one card per round.
+// In each round both players bid a card from their hand simultaneously, and the higher bid wins the prize.
+// Tied bids discard the prize. Bids are revealed at the end of each round.
+// The player with more prize points wins with a payoff of one, and the game is a draw if the points are equal.
+//
+// Simultaneous bids are encoded as hidden sequential choices:
+// the first player bids first, and the infoset of the second player excludes that bid.
+package goofspiel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Value is the value of the game for the first player, which is zero as the game is symmetric.
+	Value = 0
+
+	invalidCard = 0
+)
+
+type Goofspiel struct {
+	numCards int
+	// bids are the cards bid by the two players in turns.
+	// Card c is bid with action c-1.
+	bids []uint8
+	// prizes are the prize cards of each round, which are invalidCard until revealed.
+	prizes []int
+}
+
+func NewGoofspiel(numCards int) (Goofspiel, error) {
+	if numCards < 1 || numCards > 255 {
+		return Goofspiel{}, errors.Errorf("invalid number of cards %d", numCards)
+	}
+	g := Goofspiel{
+		numCards: numCards,
+		bids:     make([]uint8, 0),
+		prizes:   make([]int, numCards),
+	}
+	for i := range g.prizes {
+		g.prizes[i] = invalidCard
+	}
+	return g, nil
+}
+
+func (g Goofspiel) NumPlayers() int {
+	return 2
+}
+
+func (g Goofspiel) CurPlayer() int {
+	return len(g.bids) % 2
+}
+
+func (g Goofspiel) round() int {
+	return len(g.bids) / 2
+}
+
+func (g Goofspiel) InfosetLen() int {
+	return len(g.infoset())
+}
+
+// Infoset writes the player, and the prize and both bids of each round,
+// such as "1|3:1,2 1:" for the second player in the second round.
+// The bid of the first player in the current round is hidden.
+func (g Goofspiel) Infoset(outInfoset []uint8) {
+	copy(outInfoset, g.infoset())
+}
+
+func (g Goofspiel) infoset() string {
+	rounds := make([]string, 0, g.round()+1)
+	for r := 0; r < g.round(); r++ {
+		rounds = append(rounds, fmt.Sprintf("%d:%d,%d", g.prizes[r], g.bids[2*r]+1, g.bids[2*r+1]+1))
+	}
+	rounds = append(rounds, fmt.Sprintf("%d:", g.prizes[g.round()]))
+	return fmt.Sprintf("%d|%s", g.CurPlayer(), strings.Join(rounds, " "))
+}
+
+func (g Goofspiel) IsTerminal() bool {
+	return len(g.bids) == 2*g.numCards
+}
+
+func (g Goofspiel) Payoff(outPayoff []float64) {
+	var points [2]int
+	for r := 0; r < g.numCards; r++ {
+		bid0, bid1 := g.bids[2*r], g.bids[2*r+1]
+		switch {
+		case bid0 > bid1:
+			points[0] += g.prizes[r]
+		case bid0 < bid1:
+			points[1] += g.prizes[r]
+		}
+	}
+
+	var payoff float64
+	switch {
+	case points[0] > points[1]:
+		payoff = 1
+	case points[0] < points[1]:
+		payoff = -1
+	}
+	outPayoff[0] = payoff
+	outPayoff[1] = -payoff
+}
+
+// IsChanceNode reports whether the prize of the current round is yet to be revealed.
+func (g Goofspiel) IsChanceNode() bool {
+	return !g.IsTerminal() && g.prizes[g.round()] == invalidCard
+}
+
+// SampleChance reveals the prize of the current round with an outcome of src.
+func (g Goofspiel) SampleChance(src chance.Source) error {
+	o, err := src.Outcome(g)
+	if err != nil {
+		return errors.Wrap(err, "Outcome")
+	}
+	g.Chance(o)
+	return nil
+}
+
+// ChanceLen returns the number of prize cards not yet revealed, which are equally likely.
+func (g Goofspiel) ChanceLen() int {
+	return g.numCards - g.round()
+}
+
+func (g Goofspiel) ChanceProb(outcome int) float64 {
+	return 1 / float64(g.ChanceLen())
+}
+
+// Chance reveals the outcome-th prize card not yet revealed.
+// Like SampleChance, it writes to the prizes shared by all copies of g.
+func (g Goofspiel) Chance(outcome int) {
+	for c := 1; c <= g.numCards; c++ {
+		revealed := false
+		for _, p := range g.prizes[:g.round()] {
+			if p == c {
+				revealed = true
+			}
+		}
+		if revealed {
+			continue
+		}
+		if outcome == 0 {
+			g.prizes[g.round()] = c
+			return
+		}
+		outcome--
+	}
+}
+
+func (g Goofspiel) ActionsLen() int {
+	return g.numCards - g.round()
+}
+
+// Actions writes the cards still in the hand of the current player.
+func (g Goofspiel) Actions(outActions []uint8) {
+	player := g.CurPlayer()
+	cursor := 0
+	for a := 0; a < g.numCards; a++ {
+		played := false
+		for i := player; i < len(g.bids); i += 2 {
+			if int(g.bids[i]) == a {
+				played = true
+			}
+		}
+		if played {
+			continue
+		}
+		outActions[cursor] = uint8(a)
+		cursor++
+	}
+}
+
+// Play returns the state after the current player bids with action a.
+// As with kuhn.Kuhn, the returned state shares its history with g.
+func (g Goofspiel) Play(a uint8) Goofspiel {
+	g.bids = append(g.bids, a)
+	return g
+}
+
+// Clone returns a copy of g that shares no bids or prizes with it.
+func (g Goofspiel) Clone() Goofspiel {
+	bids := make([]uint8, len(g.bids))
+	copy(bids, g.bids)
+	g.bids = bids
+
+	prizes := make([]int, len(g.prizes))
+	copy(prizes, g.prizes)
+	g.prizes = prizes
+	return g
+}
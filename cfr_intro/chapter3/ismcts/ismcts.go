@@ -0,0 +1,278 @@
+// Package ismcts implements an agent that searches online with single observer information set Monte Carlo tree search,
+// from "Information Set Monte Carlo Tree Search" by Cowling, Powley and Whitehouse.
+//
+// In Kuhn poker and Dudo, all actions are public and all chance happens at the deal.
+// Before every decision the agent enumerates the deals consistent with its own cards or dices,
+// weighted by its beliefs about the opponents, and every simulation samples one of these determinisations.
+// Simulations share one UCT tree over the public actions, so that statistics are gathered per information set
+// rather than per determinisation.
+package ismcts
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	// Simulations is the number of simulations per decision.
+	Simulations int
+	// Exploration is the UCT exploration constant, which should grow with the range of the payoffs.
+	Exploration float64
+	// Belief, if not nil, models the opponents, weighting each deal by how likely the opponents would have played the history with it.
+	// Otherwise, deals are weighted by their chance probabilities only.
+	Belief strategy.Policy
+	// Rollout plays out the game from the leaves of the search tree, and is uniformly random if nil.
+	Rollout strategy.Policy
+	// Seed seeds the sampling of determinisations, chance nodes and rollouts, so that searches can be reproduced.
+	// A random seed is used if zero.
+	Seed int64
+}
+
+func DefaultConfig() Config {
+	return Config{Simulations: 1000, Exploration: math.Sqrt2}
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Simulations < 1 {
+		return errors.Errorf("invalid number of simulations %d", cfg.Simulations)
+	}
+	if cfg.Exploration < 0 {
+		return errors.Errorf("negative exploration %f", cfg.Exploration)
+	}
+	return nil
+}
+
+type Agent struct {
+	cfg  Config
+	root tree.Game
+	// chance samples the determinisations, the chance nodes and the rollout actions of simulations.
+	chance *chance.Live
+}
+
+// NewAgent returns an agent playing the Kuhn or Dudo game of root.
+func NewAgent(root tree.Game, cfg Config) (*Agent, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Validate")
+	}
+	switch root.(type) {
+	case games.Kuhn, games.Dudo:
+	default:
+		return nil, errors.Errorf("unsupported game %T", root)
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	agent := &Agent{cfg: cfg, root: root, chance: chance.NewLive(seed)}
+	return agent, nil
+}
+
+// history returns the actions taken in game as indices into the actions of each state.
+func history(root, game tree.Game) []int {
+	var actions []int
+	switch g := game.(type) {
+	case games.Kuhn:
+		// Kuhn actions are their own indices.
+		for _, a := range g.State.History() {
+			actions = append(actions, int(a))
+		}
+	case games.Dudo:
+		// Replay the claims to find their indices.
+		state := root.Chance(0).(games.Dudo)
+		for _, a := range g.State.History() {
+			values := make([]uint16, state.ActionsLen())
+			state.Actions(values)
+			for i, v := range values {
+				if v == a {
+					actions = append(actions, i)
+					break
+				}
+			}
+			state = state.Play(actions[len(actions)-1]).(games.Dudo)
+		}
+	}
+	return actions
+}
+
+// determinisations returns the states consistent with the infoset of the current player of game,
+// and their probabilities given the beliefs about the opponents.
+func (agent *Agent) determinisations(game tree.Game, belief strategy.Policy) ([]tree.Game, []float64) {
+	player := game.CurPlayer()
+	infoset := game.Infoset()
+	actions := history(agent.root, game)
+
+	states := make([]tree.Game, 0)
+	weights := make([]float64, 0)
+	for o := 0; o < agent.root.ChanceLen(); o++ {
+		state := agent.root.Chance(o)
+		weight := agent.root.ChanceProb(o)
+		for _, aIdx := range actions {
+			if belief != nil && state.CurPlayer() != player {
+				weight *= belief.Probs(state)[aIdx]
+			}
+			state = state.Play(aIdx)
+		}
+		if weight > 0 && state.Infoset() == infoset {
+			states = append(states, state)
+			weights = append(weights, weight)
+		}
+	}
+	return states, normalize(weights)
+}
+
+// node is a node of the search tree, which is an infoset of the player to move as all actions are public.
+type node struct {
+	children []*node
+	// visits and values are the number of visits of each action and the total payoffs of the player to move.
+	visits []int
+	values []float64
+	n      int
+}
+
+func (nd *node) expand(numActions int) {
+	nd.children = make([]*node, numActions)
+	nd.visits = make([]int, numActions)
+	nd.values = make([]float64, numActions)
+}
+
+// selectAction returns the action with the highest upper confidence bound, trying unvisited actions first.
+func (nd *node) selectAction(exploration float64) int {
+	best, bestUCB := 0, math.Inf(-1)
+	logN := math.Log(float64(nd.n))
+	for a, v := range nd.visits {
+		if v == 0 {
+			return a
+		}
+		ucb := nd.values[a]/float64(v) + exploration*math.Sqrt(logN/float64(v))
+		if ucb > bestUCB {
+			best, bestUCB = a, ucb
+		}
+	}
+	return best
+}
+
+type step struct {
+	node   *node
+	aIdx   int
+	player int
+}
+
+// simulate runs one simulation from state down the tree at nd, and backs up the payoffs.
+func (agent *Agent) simulate(nd *node, state tree.Game) {
+	path := make([]step, 0)
+	for !state.IsTerminal() {
+		if state.IsChanceNode() {
+			state = state.Chance(agent.chance.Sample(state))
+			continue
+		}
+		if nd == nil {
+			break
+		}
+
+		// Expand a leaf, take one action from it, and roll out.
+		leaf := nd.visits == nil
+		if leaf {
+			nd.expand(state.ActionsLen())
+		}
+		aIdx := nd.selectAction(agent.cfg.Exploration)
+		path = append(path, step{node: nd, aIdx: aIdx, player: state.CurPlayer()})
+		state = state.Play(aIdx)
+		if leaf {
+			nd = nil
+			continue
+		}
+		if nd.children[aIdx] == nil {
+			nd.children[aIdx] = &node{}
+		}
+		nd = nd.children[aIdx]
+	}
+
+	payoff := agent.rollout(state)
+	for _, s := range path {
+		s.node.n++
+		s.node.visits[s.aIdx]++
+		s.node.values[s.aIdx] += payoff[s.player]
+	}
+}
+
+func (agent *Agent) rollout(state tree.Game) []float64 {
+	for !state.IsTerminal() {
+		if state.IsChanceNode() {
+			state = state.Chance(agent.chance.Sample(state))
+			continue
+		}
+		var probs distribution
+		if agent.cfg.Rollout != nil {
+			probs = agent.cfg.Rollout.Probs(state)
+		} else {
+			probs = make(distribution, state.ActionsLen())
+			for a := range probs {
+				probs[a] = 1 / float64(len(probs))
+			}
+		}
+		state = state.Play(agent.chance.Sample(probs))
+	}
+	payoff := make([]float64, state.NumPlayers())
+	state.Payoff(payoff)
+	return payoff
+}
+
+// Search returns the number of visits of each action at game after the simulations.
+func (agent *Agent) Search(game tree.Game) []int {
+	states, probs := agent.determinisations(game, agent.cfg.Belief)
+	if len(states) == 0 {
+		// The beliefs rule out the actual history, so fall back to the chance probabilities.
+		states, probs = agent.determinisations(game, nil)
+	}
+
+	root := &node{}
+	for i := 0; i < agent.cfg.Simulations; i++ {
+		state := states[agent.chance.Sample(distribution(probs))]
+		agent.simulate(root, state)
+	}
+	return root.visits
+}
+
+// distribution is a chance node whose outcomes are drawn with the given probabilities,
+// such as the determinisations of a search or the actions of a rollout.
+type distribution []float64
+
+func (d distribution) ChanceLen() int {
+	return len(d)
+}
+
+func (d distribution) ChanceProb(outcome int) float64 {
+	return d[outcome]
+}
+
+func normalize(weights []float64) []float64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	probs := make([]float64, len(weights))
+	for i, w := range weights {
+		probs[i] = w / sum
+	}
+	return probs
+}
+
+// Probs searches game and returns the pure strategy of the most visited action.
+func (agent *Agent) Probs(game tree.Game) []float64 {
+	visits := agent.Search(game)
+	best := 0
+	for a, v := range visits {
+		if v > visits[best] {
+			best = a
+		}
+	}
+	probs := make([]float64, len(visits))
+	probs[best] = 1
+	return probs
+}
@@ -0,0 +1,388 @@
+// Package kuhn implements Kuhn poker with the same state interface as package dudo.
+//
+// https://www.aaai.org/Papers/AAAI/2005/AAAI05-123.pdf
+//
+// Besides the standard game, Config generalises Kuhn poker to larger decks, other ante and bet sizes,
+// raises, and more than two players, which gives a ladder of games of increasing difficulty.
+package kuhn
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Pass checks, or folds when facing a bet.
+	Pass = 0
+	// Bet bets, or calls when facing a bet.
+	Bet = 1
+	// Raise raises a bet, and is only allowed if Config.MaxBets is larger than one.
+	Raise      = 2
+	NumActions = 3
+
+	// Value is the value of the standard game for the first player.
+	Value = -1.0 / 18
+
+	invalidCard = 0
+)
+
+// Config are the rules of a generalised Kuhn poker.
+type Config struct {
+	// NumCards is the size of the deck, whose cards are ranked from 1 to NumCards.
+	NumCards   int
+	NumPlayers int
+	Ante       int
+	BetSize    int
+	// MaxBets is the number of bets and raises allowed, one in the standard game.
+	MaxBets int
+}
+
+// DefaultConfig returns the configuration of the standard game.
+func DefaultConfig() Config {
+	cfg := Config{
+		NumCards:   3,
+		NumPlayers: 2,
+		Ante:       1,
+		BetSize:    1,
+		MaxBets:    1,
+	}
+	return cfg
+}
+
+func (cfg Config) Validate() error {
+	if cfg.NumPlayers < 2 {
+		return errors.Errorf("need at least 2 players, got %d", cfg.NumPlayers)
+	}
+	if cfg.NumCards < cfg.NumPlayers {
+		return errors.Errorf("%d cards cannot be dealt to %d players", cfg.NumCards, cfg.NumPlayers)
+	}
+	if cfg.Ante < 1 || cfg.BetSize < 1 {
+		return errors.Errorf("ante %d and bet size %d must be positive", cfg.Ante, cfg.BetSize)
+	}
+	if cfg.MaxBets < 1 {
+		return errors.Errorf("max bets %d must be positive", cfg.MaxBets)
+	}
+	return nil
+}
+
+type Kuhn struct {
+	cfg     Config
+	history []uint8
+	cards   []int
+}
+
+func NewKuhn() Kuhn {
+	kuhn, err := NewKuhnConfig(DefaultConfig())
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+	return kuhn
+}
+
+func NewKuhnConfig(cfg Config) (Kuhn, error) {
+	if err := cfg.Validate(); err != nil {
+		return Kuhn{}, errors.Wrap(err, "Validate")
+	}
+	kuhn := Kuhn{
+		cfg:     cfg,
+		history: make([]uint8, 0),
+		cards:   make([]int, cfg.NumPlayers),
+	}
+	for p := range kuhn.cards {
+		kuhn.cards[p] = invalidCard
+	}
+	return kuhn, nil
+}
+
+func (kuhn Kuhn) Config() Config {
+	return kuhn.cfg
+}
+
+// Card returns the card of player, which is invalid before the deal.
+func (kuhn Kuhn) Card(player int) int {
+	return kuhn.cards[player]
+}
+
+// History returns the actions taken so far.
+func (kuhn Kuhn) History() []uint8 {
+	return kuhn.history
+}
+
+// FacingBet reports whether the current player faces a bet, in which case Pass folds and Bet calls.
+func (kuhn Kuhn) FacingBet() bool {
+	return kuhn.replay().numBets > 0
+}
+
+// ActionString returns a human readable action, "pass", "bet" or "raise".
+func (kuhn Kuhn) ActionString(a uint8) string {
+	switch a {
+	case Pass:
+		return "pass"
+	case Bet:
+		return "bet"
+	case Raise:
+		return "raise"
+	}
+	return fmt.Sprintf("invalid(%d)", a)
+}
+
+// betting is the state of the betting, which is replayed from the history.
+type betting struct {
+	// player is the player to act.
+	player  int
+	numBets int
+	// contrib is the chips each player has put into the pot.
+	contrib   []int
+	folded    []bool
+	numActive int
+	// pending is the number of players who have yet to act on the last bet, or to act at all if there is no bet.
+	pending int
+}
+
+func (kuhn Kuhn) replay() betting {
+	numPlayers := kuhn.cfg.NumPlayers
+	b := betting{
+		contrib:   make([]int, numPlayers),
+		folded:    make([]bool, numPlayers),
+		numActive: numPlayers,
+		pending:   numPlayers,
+	}
+	for p := range b.contrib {
+		b.contrib[p] = kuhn.cfg.Ante
+	}
+
+	for _, a := range kuhn.history {
+		maxContrib := kuhn.cfg.Ante + b.numBets*kuhn.cfg.BetSize
+		switch {
+		case a == Pass && b.numBets > 0:
+			b.folded[b.player] = true
+			b.numActive--
+		case a == Bet && b.numBets > 0:
+			b.contrib[b.player] = maxContrib
+		case a == Bet || a == Raise:
+			b.contrib[b.player] = maxContrib + kuhn.cfg.BetSize
+			b.numBets++
+			// Everyone else still in the hand has to respond to the bet.
+			b.pending = b.numActive
+		}
+		b.pending--
+
+		// Move to the next player still in the hand.
+		for i := 0; i < numPlayers; i++ {
+			b.player = (b.player + 1) % numPlayers
+			if !b.folded[b.player] {
+				break
+			}
+		}
+	}
+	return b
+}
+
+func (kuhn Kuhn) NumPlayers() int {
+	return len(kuhn.cards)
+}
+
+func (kuhn Kuhn) CurPlayer() int {
+	return kuhn.replay().player
+}
+
+func (kuhn Kuhn) InfosetLen() int {
+	return len(strconv.Itoa(kuhn.cards[kuhn.CurPlayer()])) + len(kuhn.history)
+}
+
+// Infoset writes the same infoset as section3.4, such as "2pb",
+// with 'r' for raises in games that allow them.
+func (kuhn Kuhn) Infoset(outInfoset []uint8) {
+	card := strconv.Itoa(kuhn.cards[kuhn.CurPlayer()])
+	cursor := copy(outInfoset, card)
+	for _, a := range kuhn.history {
+		switch a {
+		case Pass:
+			outInfoset[cursor] = 'p'
+		case Bet:
+			outInfoset[cursor] = 'b'
+		case Raise:
+			outInfoset[cursor] = 'r'
+		}
+		cursor++
+	}
+}
+
+func (kuhn Kuhn) IsTerminal() bool {
+	b := kuhn.replay()
+	return b.pending == 0 || b.numActive == 1
+}
+
+// Payoff pays the pot to the highest card among the players who have not folded.
+func (kuhn Kuhn) Payoff(outPayoff []float64) {
+	b := kuhn.replay()
+
+	winner := -1
+	pot := 0
+	for p, c := range b.contrib {
+		pot += c
+		outPayoff[p] = -float64(c)
+		if b.folded[p] {
+			continue
+		}
+		if winner == -1 || kuhn.cards[p] > kuhn.cards[winner] {
+			winner = p
+		}
+	}
+	outPayoff[winner] += float64(pot)
+}
+
+func (kuhn Kuhn) IsChanceNode() bool {
+	return kuhn.cards[0] == invalidCard
+}
+
+// SampleChance deals the cards with an outcome of src.
+func (kuhn Kuhn) SampleChance(src chance.Source) error {
+	o, err := src.Outcome(kuhn)
+	if err != nil {
+		return errors.Wrap(err, "Outcome")
+	}
+	kuhn.Chance(o)
+	return nil
+}
+
+// ChanceLen returns the number of equally likely deals.
+func (kuhn Kuhn) ChanceLen() int {
+	size := 1
+	for p := range kuhn.cards {
+		size *= kuhn.cfg.NumCards - p
+	}
+	return size
+}
+
+func (kuhn Kuhn) ChanceProb(outcome int) float64 {
+	return 1 / float64(kuhn.ChanceLen())
+}
+
+// Chance deals the outcome-th deal in [0, ChanceLen()).
+// Like SampleChance, it writes to the cards shared by all copies of kuhn.
+func (kuhn Kuhn) Chance(outcome int) {
+	copy(kuhn.cards, kuhn.deal(outcome))
+}
+
+// deal returns the cards of each player in the outcome-th deal.
+func (kuhn Kuhn) deal(outcome int) []int {
+	cards := make([]int, len(kuhn.cards))
+	for p := range cards {
+		remaining := kuhn.cfg.NumCards - p
+		idx := outcome % remaining
+		outcome /= remaining
+
+		// Deal the idx-th card not yet dealt to the previous players.
+		card := 1
+		for ; ; card++ {
+			if isDealt(cards[:p], card) {
+				continue
+			}
+			if idx == 0 {
+				break
+			}
+			idx--
+		}
+		cards[p] = card
+	}
+	return cards
+}
+
+func isDealt(cards []int, card int) bool {
+	for _, c := range cards {
+		if c == card {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDeal returns the order of the deck in the outcome-th deal, such as "312" for card 3 to the first player and card 1 to the second,
+// which is a line of the rand.txt read by KuhnTrainer.java.
+// The cards not dealt follow in increasing order.
+func (kuhn Kuhn) FormatDeal(outcome int) string {
+	cards := kuhn.deal(outcome)
+	for card := 1; card <= kuhn.cfg.NumCards; card++ {
+		if !isDealt(cards, card) {
+			cards = append(cards, card)
+		}
+	}
+	var s string
+	for _, c := range cards {
+		s += strconv.Itoa(c)
+	}
+	return s
+}
+
+// ParseDeal returns the deal in which the players are dealt the first cards of the order s, as formatted by FormatDeal.
+func (kuhn Kuhn) ParseDeal(s string) (int, error) {
+	if kuhn.cfg.NumCards > 9 {
+		return -1, errors.Errorf("%d cards are not single digits", kuhn.cfg.NumCards)
+	}
+	if len(s) < len(kuhn.cards) {
+		return -1, errors.Errorf("deal %q has less than %d cards", s, len(kuhn.cards))
+	}
+	cards := make([]int, len(kuhn.cards))
+	outcome, base := 0, 1
+	for p := range cards {
+		card, err := strconv.Atoi(s[p : p+1])
+		if err != nil {
+			return -1, errors.Wrap(err, "Atoi")
+		}
+		if card < 1 || card > kuhn.cfg.NumCards || isDealt(cards[:p], card) {
+			return -1, errors.Errorf("invalid card %d in deal %q", card, s)
+		}
+		cards[p] = card
+
+		// card is the idx-th card not yet dealt to the previous players.
+		idx := 0
+		for c := 1; c < card; c++ {
+			if !isDealt(cards[:p], c) {
+				idx++
+			}
+		}
+		outcome += idx * base
+		base *= kuhn.cfg.NumCards - p
+	}
+	return outcome, nil
+}
+
+func (kuhn Kuhn) ActionsLen() int {
+	b := kuhn.replay()
+	if b.numBets > 0 && b.numBets < kuhn.cfg.MaxBets {
+		return 3
+	}
+	return 2
+}
+
+// Actions writes Pass and Bet, followed by Raise if facing a bet that can still be raised.
+func (kuhn Kuhn) Actions(outActions []uint8) {
+	outActions[0] = Pass
+	outActions[1] = Bet
+	if kuhn.ActionsLen() > 2 {
+		outActions[2] = Raise
+	}
+}
+
+// Play returns the state after the current player takes action a.
+// As with dudo.Dudo, the returned state shares its history with kuhn.
+func (kuhn Kuhn) Play(a uint8) Kuhn {
+	kuhn.history = append(kuhn.history, a)
+	return kuhn
+}
+
+// Clone returns a copy of kuhn that shares no history or cards with it.
+func (kuhn Kuhn) Clone() Kuhn {
+	history := make([]uint8, len(kuhn.history))
+	copy(history, kuhn.history)
+	kuhn.history = history
+
+	cards := make([]int, len(kuhn.cards))
+	copy(cards, kuhn.cards)
+	kuhn.cards = cards
+	return kuhn
+}
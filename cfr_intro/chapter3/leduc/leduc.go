@@ -0,0 +1,305 @@
+// Package leduc implements Leduc Hold'em with the same state interface as package kuhn.
+//
+// The deck has two suits of three ranks, each player antes one chip and is dealt a private card,
+// and there are two betting rounds separated by the deal of a public card.
+// Raises are two chips in the first round and four in the second, with at most two raises per round.
+// At showdown, a pair with the public card wins, otherwise the higher private card wins.
+//
+// The game is from "Bayes' Bluff: Opponent Modelling in Poker" by Southey et al.
+package leduc
+
+import (
+	"fmt"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+const (
+	Fold       = 0
+	Call       = 1
+	Raise      = 2
+	NumActions = 3
+
+	// Value is the value of the game for the first player.
+	Value = -0.0856
+
+	numRanks    = 3
+	numCards    = 2 * numRanks
+	numRounds   = 2
+	maxRaises   = 2
+	ante        = 1
+	invalidCard = -1
+	// publicCard is the index of the public card in cards.
+	publicCard = 2
+)
+
+var (
+	raiseSizes = [numRounds]int{2, 4}
+	rankNames  = [numRanks]uint8{'J', 'Q', 'K'}
+	// suitNames are the suits of the cards, named as in the ACPC protocol.
+	suitNames = [2]uint8{'s', 'h'}
+)
+
+type Leduc struct {
+	history []uint8
+	// cards are the private cards of the two players followed by the public card.
+	// Card c has rank c/2.
+	cards []int
+
+	round int
+	// roundActions is the number of actions in the current round.
+	roundActions int
+	raises       int
+	bets         [2]int
+	folded       bool
+}
+
+func NewLeduc() Leduc {
+	leduc := Leduc{
+		history: make([]uint8, 0),
+		cards:   []int{invalidCard, invalidCard, invalidCard},
+		bets:    [2]int{ante, ante},
+	}
+	return leduc
+}
+
+// PrivateCard returns the private card of player, or -1 before the deal.
+// Card c has rank c/2 and suit c%2.
+func (leduc Leduc) PrivateCard(player int) int {
+	return leduc.cards[player]
+}
+
+// PublicCard returns the public card, or -1 before it is dealt.
+func (leduc Leduc) PublicCard() int {
+	return leduc.cards[publicCard]
+}
+
+// CardString returns the rank and suit of card c, such as "Qh".
+func CardString(c int) string {
+	return string([]uint8{rankNames[c/2], suitNames[c%2]})
+}
+
+// ActionString returns a human readable action, "fold", "call" or "raise".
+func (leduc Leduc) ActionString(a uint8) string {
+	switch a {
+	case Fold:
+		return "fold"
+	case Call:
+		return "call"
+	case Raise:
+		return "raise"
+	}
+	return fmt.Sprintf("invalid(%d)", a)
+}
+
+func (leduc Leduc) NumPlayers() int {
+	return 2
+}
+
+// CurPlayer returns the player to act, the first player always acting first in each round.
+func (leduc Leduc) CurPlayer() int {
+	return leduc.roundActions % 2
+}
+
+func (leduc Leduc) InfosetLen() int {
+	size := 1 // for the private card
+	if leduc.round > 0 {
+		size += 1 // for the public card
+	}
+	size += 1 // for ':'
+	size += len(leduc.history)
+	if leduc.round > 0 {
+		size += 1 // for the '/' between rounds
+	}
+	return size
+}
+
+// Infoset writes the private rank, the public rank if dealt, and the betting of each round,
+// such as "K:rc" in the first round and "KJ:rc/r" in the second.
+func (leduc Leduc) Infoset(outInfoset []uint8) {
+	cursor := 0
+	outInfoset[cursor] = rankNames[leduc.cards[leduc.CurPlayer()]/2]
+	cursor++
+	if leduc.round > 0 {
+		outInfoset[cursor] = rankNames[leduc.cards[publicCard]/2]
+		cursor++
+	}
+	outInfoset[cursor] = ':'
+	cursor++
+
+	firstRoundLen := len(leduc.history) - leduc.roundActions
+	for i, a := range leduc.history {
+		if i == firstRoundLen && leduc.round > 0 {
+			outInfoset[cursor] = '/'
+			cursor++
+		}
+		switch a {
+		case Fold:
+			outInfoset[cursor] = 'f'
+		case Call:
+			outInfoset[cursor] = 'c'
+		case Raise:
+			outInfoset[cursor] = 'r'
+		}
+		cursor++
+	}
+	if firstRoundLen == len(leduc.history) && leduc.round > 0 {
+		outInfoset[cursor] = '/'
+	}
+}
+
+func (leduc Leduc) IsTerminal() bool {
+	return leduc.folded || leduc.round == numRounds
+}
+
+func (leduc Leduc) Payoff(outPayoff []float64) {
+	if leduc.folded {
+		// The player who folded is the one who acted last.
+		folder := (leduc.roundActions - 1) % 2
+		outPayoff[folder] = -float64(leduc.bets[folder])
+		outPayoff[1-folder] = float64(leduc.bets[folder])
+		return
+	}
+
+	// At showdown, both players have bet the same.
+	var payoff float64
+	switch strength0, strength1 := leduc.handStrength(0), leduc.handStrength(1); {
+	case strength0 > strength1:
+		payoff = float64(leduc.bets[0])
+	case strength0 < strength1:
+		payoff = -float64(leduc.bets[0])
+	}
+	outPayoff[0] = payoff
+	outPayoff[1] = -payoff
+}
+
+// handStrength ranks a pair above all unpaired hands, which are ranked by the private card.
+func (leduc Leduc) handStrength(player int) int {
+	rank := leduc.cards[player] / 2
+	if rank == leduc.cards[publicCard]/2 {
+		return numRanks + rank
+	}
+	return rank
+}
+
+// IsChanceNode reports whether the private cards, or the public card after the first round, are yet to be dealt.
+func (leduc Leduc) IsChanceNode() bool {
+	if leduc.cards[0] == invalidCard {
+		return true
+	}
+	return leduc.round == 1 && !leduc.folded && leduc.cards[publicCard] == invalidCard
+}
+
+// SampleChance deals the private cards, or the public card with an outcome of src.
+func (leduc Leduc) SampleChance(src chance.Source) error {
+	o, err := src.Outcome(leduc)
+	if err != nil {
+		return errors.Wrap(err, "Outcome")
+	}
+	leduc.Chance(o)
+	return nil
+}
+
+// ChanceLen returns the number of equally likely deals of the private cards, or of the public card.
+func (leduc Leduc) ChanceLen() int {
+	if leduc.cards[0] == invalidCard {
+		return numCards * (numCards - 1)
+	}
+	return numCards - 2
+}
+
+func (leduc Leduc) ChanceProb(outcome int) float64 {
+	return 1 / float64(leduc.ChanceLen())
+}
+
+// Chance deals the outcome-th deal in [0, ChanceLen()).
+// Like SampleChance, it writes to the cards shared by all copies of leduc.
+func (leduc Leduc) Chance(outcome int) {
+	if leduc.cards[0] == invalidCard {
+		leduc.cards[0] = outcome / (numCards - 1)
+		leduc.cards[1] = outcome % (numCards - 1)
+		if leduc.cards[1] >= leduc.cards[0] {
+			leduc.cards[1]++
+		}
+		return
+	}
+
+	// Skip the private cards to find the outcome-th remaining card.
+	for c := 0; c < numCards; c++ {
+		if c == leduc.cards[0] || c == leduc.cards[1] {
+			continue
+		}
+		if outcome == 0 {
+			leduc.cards[publicCard] = c
+			return
+		}
+		outcome--
+	}
+}
+
+func (leduc Leduc) ActionsLen() int {
+	size := 1 // for call
+	if leduc.facingRaise() {
+		size += 1
+	}
+	if leduc.raises < maxRaises {
+		size += 1
+	}
+	return size
+}
+
+// Actions writes fold if facing a raise, call, and raise if the cap is not reached.
+func (leduc Leduc) Actions(outActions []uint8) {
+	cursor := 0
+	if leduc.facingRaise() {
+		outActions[cursor] = Fold
+		cursor++
+	}
+	outActions[cursor] = Call
+	cursor++
+	if leduc.raises < maxRaises {
+		outActions[cursor] = Raise
+	}
+}
+
+func (leduc Leduc) facingRaise() bool {
+	return leduc.bets[0] != leduc.bets[1]
+}
+
+// Play returns the state after the current player takes action a.
+// As with kuhn.Kuhn, the returned state shares its history with leduc.
+func (leduc Leduc) Play(a uint8) Leduc {
+	player := leduc.CurPlayer()
+	leduc.history = append(leduc.history, a)
+	leduc.roundActions++
+
+	switch a {
+	case Fold:
+		leduc.folded = true
+	case Call:
+		leduc.bets[player] = leduc.bets[1-player]
+		// A call ends the round, unless it is a check that opens the round.
+		if leduc.roundActions >= 2 {
+			leduc.round++
+			leduc.roundActions = 0
+			leduc.raises = 0
+		}
+	case Raise:
+		leduc.bets[player] = leduc.bets[1-player] + raiseSizes[leduc.round]
+		leduc.raises++
+	}
+	return leduc
+}
+
+// Clone returns a copy of leduc that shares no history or cards with it.
+func (leduc Leduc) Clone() Leduc {
+	history := make([]uint8, len(leduc.history))
+	copy(history, leduc.history)
+	leduc.history = history
+
+	cards := make([]int, len(leduc.cards))
+	copy(cards, leduc.cards)
+	leduc.cards = cards
+	return leduc
+}
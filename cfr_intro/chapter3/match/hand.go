@@ -0,0 +1,102 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Hand is a played game, recorded as the index of every chance outcome and action from the root.
+// It is logged as a line such as
+//
+//	HAND:a|b:4,0,1:-2|2
+//
+// which lists the players by position, the steps, and the payoffs of each position.
+type Hand struct {
+	Players []string
+	Steps   []int
+	// Probs, if not nil, are the probabilities of the actions at each step, which are nil for chance outcomes.
+	// They are not logged in HAND lines, but kept by package record.
+	Probs   [][]float64
+	Payoffs []float64
+}
+
+func (h Hand) String() string {
+	steps := make([]string, 0, len(h.Steps))
+	for _, s := range h.Steps {
+		steps = append(steps, strconv.Itoa(s))
+	}
+	payoffs := make([]string, 0, len(h.Payoffs))
+	for _, p := range h.Payoffs {
+		payoffs = append(payoffs, strconv.FormatFloat(p, 'g', -1, 64))
+	}
+	return fmt.Sprintf("HAND:%s:%s:%s", strings.Join(h.Players, "|"), strings.Join(steps, ","), strings.Join(payoffs, "|"))
+}
+
+func ParseHand(line string) (Hand, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 4 || fields[0] != "HAND" {
+		return Hand{}, errors.Errorf("invalid hand %q", line)
+	}
+	h := Hand{Players: strings.Split(fields[1], "|")}
+	if fields[2] != "" {
+		for _, s := range strings.Split(fields[2], ",") {
+			step, err := strconv.Atoi(s)
+			if err != nil {
+				return Hand{}, errors.Wrap(err, "step")
+			}
+			h.Steps = append(h.Steps, step)
+		}
+	}
+	for _, s := range strings.Split(fields[3], "|") {
+		p, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Hand{}, errors.Wrap(err, "payoff")
+		}
+		h.Payoffs = append(h.Payoffs, p)
+	}
+	return h, nil
+}
+
+// Position returns the position of player in h, or -1 if she did not play.
+func (h Hand) Position(player string) int {
+	for p, name := range h.Players {
+		if name == player {
+			return p
+		}
+	}
+	return -1
+}
+
+// Replay returns the states of h from the root to the end of the game.
+func (h Hand) Replay(root tree.Game) ([]tree.Game, error) {
+	states := []tree.Game{root}
+	game := root
+	for i, s := range h.Steps {
+		var n int
+		switch {
+		case game.IsTerminal():
+			return nil, errors.Errorf("step %d after the end of the game", i)
+		case game.IsChanceNode():
+			n = game.ChanceLen()
+		default:
+			n = game.ActionsLen()
+		}
+		if s < 0 || s >= n {
+			return nil, errors.Errorf("step %d is %d, not in [0, %d)", i, s, n)
+		}
+		if game.IsChanceNode() {
+			game = game.Chance(s)
+		} else {
+			game = game.Play(s)
+		}
+		states = append(states, game)
+	}
+	if !game.IsTerminal() {
+		return nil, errors.Errorf("game not ended after %d steps", len(h.Steps))
+	}
+	return states, nil
+}
@@ -0,0 +1,282 @@
+// Package match plays two policies against each other in a two player zero-sum game,
+// and estimates the mean payoff of the first policy with a confidence interval.
+//
+// The policies swap seats every game to remove the positional bias of games such as Kuhn poker.
+// Two variance reductions help detect small edges:
+// duplicate dealing replays the chance outcomes of each game with the seats swapped,
+// and the luck control variate subtracts the expected gain of each chance outcome under a reference profile.
+package match
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	// Games is the number of games, or of pairs of games when dealing duplicate.
+	// Without duplicate, the seats alternate every game, so Games must be even for both policies to play each seat equally.
+	Games int
+	// Duplicate plays each deal twice with the seats swapped, and scores the pair as one sample.
+	Duplicate bool
+	// Luck is a reference profile in which both players play Luck.
+	// If not nil, every chance outcome is scored by how much it changes the expected payoff of the reference profile,
+	// and this luck, which has zero mean, is subtracted from the payoff.
+	Luck strategy.Policy
+	// Log, if not nil, receives every game as a Hand, in which the first policy is named a and the second b.
+	Log io.Writer
+	// Record, if not nil, is called with every game as a Hand, including the probabilities of every decision.
+	Record func(h Hand) error
+	// Chance, if not nil, provides the chance outcomes, which are otherwise sampled by a chance.Live seeded by math/rand.
+	// The duplicate of a game replays its outcomes without consuming Chance.
+	Chance chance.Source
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Games < 1 {
+		return errors.Errorf("invalid number of games %d", cfg.Games)
+	}
+	if !cfg.Duplicate && cfg.Games%2 != 0 {
+		return errors.Errorf("odd number of games %d without duplicate", cfg.Games)
+	}
+	return nil
+}
+
+// Result is the payoff of the first policy per game.
+type Result struct {
+	// Samples is the number of samples, which are pairs of games when dealing duplicate.
+	Samples int
+	Mean    float64
+	// StdErr is the standard error of Mean.
+	StdErr float64
+}
+
+// Interval returns the confidence interval of Mean at z standard errors, such as 1.96 for 95% confidence.
+func (r Result) Interval(z float64) (float64, float64) {
+	return r.Mean - z*r.StdErr, r.Mean + z*r.StdErr
+}
+
+func (r Result) String() string {
+	lo, hi := r.Interval(1.96)
+	return fmt.Sprintf("%.4f ± %.4f, 95%% interval [%.4f, %.4f] over %d samples", r.Mean, 1.96*r.StdErr, lo, hi, r.Samples)
+}
+
+// Stats accumulates the mean and variance of samples with Welford's algorithm.
+type Stats struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func (s *Stats) Add(x float64) {
+	s.n++
+	d := x - s.mean
+	s.mean += d / float64(s.n)
+	s.m2 += d * (x - s.mean)
+}
+
+func (s *Stats) Result() Result {
+	r := Result{Samples: s.n, Mean: s.mean}
+	if s.n > 1 {
+		r.StdErr = math.Sqrt(s.m2 / float64(s.n-1) / float64(s.n))
+	}
+	return r
+}
+
+// deal records the chance outcomes of a game, so that its duplicate replays them.
+type deal struct {
+	outcomes []int
+	next     int
+}
+
+// sample returns the next recorded outcome, or takes a new one from src and records it.
+// Outcomes that are not valid at game, because the duplicate went differently, are taken anew.
+func (d *deal) sample(game tree.Game, src chance.Source) (int, error) {
+	if d.next < len(d.outcomes) && d.outcomes[d.next] < game.ChanceLen() {
+		o := d.outcomes[d.next]
+		d.next++
+		return o, nil
+	}
+	o, err := src.Outcome(game)
+	if err != nil {
+		return -1, errors.Wrap(err, "Outcome")
+	}
+	d.outcomes = append(d.outcomes[:d.next], o)
+	d.next++
+	return o, nil
+}
+
+// Value returns the expected payoffs of game when every player plays policy.
+func Value(game tree.Game, policy strategy.Policy) []float64 {
+	value := make([]float64, game.NumPlayers())
+	if game.IsTerminal() {
+		game.Payoff(value)
+		return value
+	}
+
+	var probs []float64
+	n := 0
+	if game.IsChanceNode() {
+		n = game.ChanceLen()
+	} else {
+		probs = policy.Probs(game)
+		n = len(probs)
+	}
+	for i := 0; i < n; i++ {
+		var p float64
+		var child tree.Game
+		if game.IsChanceNode() {
+			p, child = game.ChanceProb(i), game.Chance(i)
+		} else {
+			p, child = probs[i], game.Play(i)
+		}
+		if p == 0 {
+			continue
+		}
+		for player, v := range Value(child, policy) {
+			value[player] += p * v
+		}
+	}
+	return value
+}
+
+type runner struct {
+	root tree.Game
+	cfg  Config
+	// rootValue and rootChildValues cache the values under cfg.Luck of the root and of its chance outcomes,
+	// which are needed every game in games that start with the deal.
+	rootValue       []float64
+	rootChildValues map[int][]float64
+}
+
+// luck returns the change of the values of each player under cfg.Luck from game to the chance outcome o.
+func (r *runner) luck(game tree.Game, o int, depth int) []float64 {
+	var before, after []float64
+	if depth == 0 {
+		if r.rootValue == nil {
+			r.rootValue = Value(r.root, r.cfg.Luck)
+			r.rootChildValues = make(map[int][]float64)
+		}
+		before = r.rootValue
+		after = r.rootChildValues[o]
+		if after == nil {
+			after = Value(game.Chance(o), r.cfg.Luck)
+			r.rootChildValues[o] = after
+		}
+	} else {
+		before = Value(game, r.cfg.Luck)
+		after = Value(game.Chance(o), r.cfg.Luck)
+	}
+
+	luck := make([]float64, len(before))
+	for p := range luck {
+		luck[p] = after[p] - before[p]
+	}
+	return luck
+}
+
+// play plays a game with the policies in seats, and returns the payoffs of each seat with the luck subtracted.
+func (r *runner) play(seats []strategy.Policy, names []string, d *deal) ([]float64, error) {
+	luck := make([]float64, len(seats))
+	var steps []int
+	var probs [][]float64
+	game := r.root
+	for depth := 0; !game.IsTerminal(); depth++ {
+		if !game.IsChanceNode() {
+			p := seats[game.CurPlayer()].Probs(game)
+			aIdx := strategy.SampleAction(p)
+			steps = append(steps, aIdx)
+			probs = append(probs, p)
+			game = game.Play(aIdx)
+			continue
+		}
+
+		o, err := d.sample(game, r.cfg.Chance)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, o)
+		probs = append(probs, nil)
+		if r.cfg.Luck != nil {
+			for p, l := range r.luck(game, o, depth) {
+				luck[p] += l
+			}
+		}
+		game = game.Chance(o)
+	}
+
+	payoff := make([]float64, game.NumPlayers())
+	game.Payoff(payoff)
+	hand := Hand{Players: names, Steps: steps, Probs: probs, Payoffs: payoff}
+	if r.cfg.Log != nil {
+		if _, err := fmt.Fprintln(r.cfg.Log, hand); err != nil {
+			return nil, errors.Wrap(err, "Fprintln")
+		}
+	}
+	if r.cfg.Record != nil {
+		if err := r.cfg.Record(hand); err != nil {
+			return nil, errors.Wrap(err, "Record")
+		}
+	}
+	for p := range payoff {
+		payoff[p] -= luck[p]
+	}
+	return payoff, nil
+}
+
+// Run plays a against b for cfg.Games games, and returns the payoff of a.
+// If a game fails, such as when a chance.Trace runs out with chance.ErrEnd,
+// Run returns the result of the games played so far together with the error.
+func Run(root tree.Game, a, b strategy.Policy, cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, errors.Wrap(err, "Validate")
+	}
+	if root.NumPlayers() != 2 {
+		return Result{}, errors.Errorf("%d players", root.NumPlayers())
+	}
+
+	if cfg.Chance == nil {
+		cfg.Chance = chance.NewLive(rand.Int63())
+	}
+	r := &runner{root: root, cfg: cfg}
+	first, firstNames := []strategy.Policy{a, b}, []string{"a", "b"}
+	second, secondNames := []strategy.Policy{b, a}, []string{"b", "a"}
+	var s Stats
+	for i := 0; i < cfg.Games; i++ {
+		if cfg.Duplicate {
+			d := &deal{}
+			p0, err := r.play(first, firstNames, d)
+			if err != nil {
+				return s.Result(), errors.Wrapf(err, "game %d", i)
+			}
+			d.next = 0
+			p1, err := r.play(second, secondNames, d)
+			if err != nil {
+				return s.Result(), errors.Wrapf(err, "game %d", i)
+			}
+			s.Add((p0[0] + p1[1]) / 2)
+			continue
+		}
+
+		if i%2 == 0 {
+			p, err := r.play(first, firstNames, &deal{})
+			if err != nil {
+				return s.Result(), errors.Wrapf(err, "game %d", i)
+			}
+			s.Add(p[0])
+		} else {
+			p, err := r.play(second, secondNames, &deal{})
+			if err != nil {
+				return s.Result(), errors.Wrapf(err, "game %d", i)
+			}
+			s.Add(p[1])
+		}
+	}
+	return s.Result(), nil
+}
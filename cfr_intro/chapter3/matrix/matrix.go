@@ -0,0 +1,184 @@
+// Package matrix implements two player zero-sum matrix games, such as rock paper scissors,
+// with the same state interface as package kuhn.
+//
+// The simultaneous choice is encoded as a hidden sequential choice:
+// the row player chooses first, and the infoset of the column player excludes that choice.
+package matrix
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/rps"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RPSValue is the value of rock paper scissors for the row player, which is zero as the game is symmetric.
+	RPSValue = 0
+)
+
+type Config struct {
+	// Payoff is the payoff of the row player, who chooses a row, against the column player, who chooses a column.
+	Payoff [][]float64
+	// ActionNames are the names of the actions of both players, if the matrix is square.
+	// Actions are named by their indices if ActionNames is empty.
+	ActionNames []string
+}
+
+// RPS returns the config of rock paper scissors.
+func RPS() Config {
+	payoff := make([][]float64, rps.NumActions)
+	for i := range payoff {
+		payoff[i] = make([]float64, rps.NumActions)
+		for j := range payoff[i] {
+			// rps.Payoff is indexed by the action of the opponent first.
+			payoff[i][j] = rps.Payoff[j][i]
+		}
+	}
+	return Config{Payoff: payoff, ActionNames: []string{"rock", "paper", "scissors"}}
+}
+
+// ParsePayoff parses a matrix of rows separated by ';' and columns separated by ',', such as "0,-1,1;1,0,-1;-1,1,0".
+func ParsePayoff(s string) ([][]float64, error) {
+	payoff := make([][]float64, 0)
+	for _, row := range strings.Split(s, ";") {
+		cols := strings.Split(row, ",")
+		payoffRow := make([]float64, 0, len(cols))
+		for _, c := range cols {
+			p, err := strconv.ParseFloat(strings.TrimSpace(c), 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "ParseFloat")
+			}
+			payoffRow = append(payoffRow, p)
+		}
+		payoff = append(payoff, payoffRow)
+	}
+	return payoff, nil
+}
+
+func (cfg Config) Validate() error {
+	if len(cfg.Payoff) == 0 || len(cfg.Payoff) > 255 {
+		return errors.Errorf("invalid number of rows %d", len(cfg.Payoff))
+	}
+	cols := len(cfg.Payoff[0])
+	if cols == 0 || cols > 255 {
+		return errors.Errorf("invalid number of columns %d", cols)
+	}
+	for i, row := range cfg.Payoff {
+		if len(row) != cols {
+			return errors.Errorf("row %d has %d columns instead of %d", i, len(row), cols)
+		}
+	}
+	if len(cfg.ActionNames) > 0 && (len(cfg.ActionNames) != len(cfg.Payoff) || len(cfg.ActionNames) != cols) {
+		return errors.Errorf("%d action names for a %dx%d matrix", len(cfg.ActionNames), len(cfg.Payoff), cols)
+	}
+	return nil
+}
+
+type Matrix struct {
+	cfg Config
+	// history is the row followed by the column.
+	history []uint8
+}
+
+func NewMatrix(cfg Config) (Matrix, error) {
+	if err := cfg.Validate(); err != nil {
+		return Matrix{}, errors.Wrap(err, "Validate")
+	}
+	m := Matrix{cfg: cfg, history: make([]uint8, 0, 2)}
+	return m, nil
+}
+
+func (m Matrix) Config() Config {
+	return m.cfg
+}
+
+// ActionString returns the name of action a.
+func (m Matrix) ActionString(a uint8) string {
+	if int(a) < len(m.cfg.ActionNames) {
+		return m.cfg.ActionNames[a]
+	}
+	return strconv.Itoa(int(a))
+}
+
+// History returns the row and the column chosen so far.
+func (m Matrix) History() []uint8 {
+	return m.history
+}
+
+func (m Matrix) NumPlayers() int {
+	return 2
+}
+
+func (m Matrix) CurPlayer() int {
+	return len(m.history)
+}
+
+func (m Matrix) InfosetLen() int {
+	return 1
+}
+
+// Infoset writes the player only, as neither player sees the choice of the other.
+func (m Matrix) Infoset(outInfoset []uint8) {
+	outInfoset[0] = '0' + uint8(m.CurPlayer())
+}
+
+func (m Matrix) IsTerminal() bool {
+	return len(m.history) == 2
+}
+
+func (m Matrix) Payoff(outPayoff []float64) {
+	p := m.cfg.Payoff[m.history[0]][m.history[1]]
+	outPayoff[0] = p
+	outPayoff[1] = -p
+}
+
+// IsChanceNode always returns false, as there is no chance in matrix games.
+func (m Matrix) IsChanceNode() bool {
+	return false
+}
+
+func (m Matrix) SampleChance(src chance.Source) error {
+	return nil
+}
+
+func (m Matrix) ChanceLen() int {
+	return 0
+}
+
+func (m Matrix) ChanceProb(outcome int) float64 {
+	return 0
+}
+
+func (m Matrix) Chance(outcome int) {}
+
+func (m Matrix) ActionsLen() int {
+	if m.CurPlayer() == 0 {
+		return len(m.cfg.Payoff)
+	}
+	return len(m.cfg.Payoff[0])
+}
+
+// Actions writes the rows or the columns, where action a is the a-th one.
+func (m Matrix) Actions(outActions []uint8) {
+	for i := range outActions {
+		outActions[i] = uint8(i)
+	}
+}
+
+// Play returns the state after the current player chooses a.
+// As with kuhn.Kuhn, the returned state shares its history with m.
+func (m Matrix) Play(a uint8) Matrix {
+	m.history = append(m.history, a)
+	return m
+}
+
+// Clone returns a copy of m that shares no history with it.
+func (m Matrix) Clone() Matrix {
+	history := make([]uint8, len(m.history), 2)
+	copy(history, m.history)
+	m.history = history
+	return m
+}
@@ -0,0 +1,181 @@
+// Package oshizumo implements Oshi-Zumo with the same state interface as package kuhn.
+//
+// A wrestler stands in the middle of a field of 2*FieldSize+1 locations, and each player starts with Coins coins.
+// In each turn both players bid coins simultaneously, and the higher bidder pushes the wrestler one location towards her opponent.
+// Both bids are paid, and ties leave the wrestler in place.
+// A player must bid at least MinBid if she can afford it, and bids nothing otherwise.
+// The game ends when the wrestler is pushed off the field, or when neither player can afford MinBid.
+// The player on whose opposite side the wrestler ends wins with a payoff of one, and the game is a draw if it ends in the middle.
+//
+// Simultaneous bids are encoded as hidden sequential choices:
+// the first player bids first, and the infoset of the second player excludes that bid.
+// The game is from "Solving the Oshi-Zumo Game" by Michael Buro.
+package oshizumo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Value is the value of the game for the first player, which is zero as the game is symmetric.
+	Value = 0
+)
+
+type Config struct {
+	Coins     int
+	FieldSize int
+	MinBid    int
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Coins < 0 || cfg.Coins > 255 {
+		return errors.Errorf("invalid number of coins %d", cfg.Coins)
+	}
+	if cfg.FieldSize < 1 {
+		return errors.Errorf("field size %d must be positive", cfg.FieldSize)
+	}
+	// Without a positive minimum, both players could bid nothing forever.
+	if cfg.MinBid < 1 {
+		return errors.Errorf("min bid %d must be positive", cfg.MinBid)
+	}
+	return nil
+}
+
+type OshiZumo struct {
+	cfg Config
+	// bids are the bids of the two players in turns.
+	bids []uint8
+
+	coins [2]int
+	// position of the wrestler, with the first player pushing towards positive positions.
+	position int
+}
+
+func NewOshiZumo(cfg Config) (OshiZumo, error) {
+	if err := cfg.Validate(); err != nil {
+		return OshiZumo{}, errors.Wrap(err, "Validate")
+	}
+	oz := OshiZumo{
+		cfg:   cfg,
+		bids:  make([]uint8, 0),
+		coins: [2]int{cfg.Coins, cfg.Coins},
+	}
+	return oz, nil
+}
+
+func (oz OshiZumo) NumPlayers() int {
+	return 2
+}
+
+func (oz OshiZumo) CurPlayer() int {
+	return len(oz.bids) % 2
+}
+
+func (oz OshiZumo) InfosetLen() int {
+	return len(oz.infoset())
+}
+
+// Infoset writes the player and the bids of each completed turn, such as "1|2-1 0-1".
+// The bid of the first player in the current turn is hidden.
+func (oz OshiZumo) Infoset(outInfoset []uint8) {
+	copy(outInfoset, oz.infoset())
+}
+
+func (oz OshiZumo) infoset() string {
+	turns := make([]string, 0, len(oz.bids)/2)
+	for i := 0; i+1 < len(oz.bids); i += 2 {
+		turns = append(turns, fmt.Sprintf("%d-%d", oz.bids[i], oz.bids[i+1]))
+	}
+	return fmt.Sprintf("%d|%s", oz.CurPlayer(), strings.Join(turns, " "))
+}
+
+func (oz OshiZumo) IsTerminal() bool {
+	if oz.position < -oz.cfg.FieldSize || oz.position > oz.cfg.FieldSize {
+		return true
+	}
+	return oz.coins[0] < oz.cfg.MinBid && oz.coins[1] < oz.cfg.MinBid
+}
+
+func (oz OshiZumo) Payoff(outPayoff []float64) {
+	var payoff float64
+	switch {
+	case oz.position > 0:
+		payoff = 1
+	case oz.position < 0:
+		payoff = -1
+	}
+	outPayoff[0] = payoff
+	outPayoff[1] = -payoff
+}
+
+// IsChanceNode always returns false, as there is no chance in Oshi-Zumo.
+func (oz OshiZumo) IsChanceNode() bool {
+	return false
+}
+
+func (oz OshiZumo) SampleChance(src chance.Source) error {
+	return nil
+}
+
+func (oz OshiZumo) ChanceLen() int {
+	return 0
+}
+
+func (oz OshiZumo) ChanceProb(outcome int) float64 {
+	return 0
+}
+
+func (oz OshiZumo) Chance(outcome int) {}
+
+func (oz OshiZumo) ActionsLen() int {
+	coins := oz.coins[oz.CurPlayer()]
+	if coins < oz.cfg.MinBid {
+		return 1
+	}
+	return coins - oz.cfg.MinBid + 1
+}
+
+// Actions writes the bids the current player can afford, where action a bids a coins.
+func (oz OshiZumo) Actions(outActions []uint8) {
+	coins := oz.coins[oz.CurPlayer()]
+	if coins < oz.cfg.MinBid {
+		outActions[0] = 0
+		return
+	}
+	for i := range outActions {
+		outActions[i] = uint8(oz.cfg.MinBid + i)
+	}
+}
+
+// Play returns the state after the current player bids a coins.
+// As with kuhn.Kuhn, the returned state shares its history with oz.
+func (oz OshiZumo) Play(a uint8) OshiZumo {
+	oz.bids = append(oz.bids, a)
+	if len(oz.bids)%2 != 0 {
+		return oz
+	}
+
+	// Both players have bid, so resolve the turn.
+	bid0, bid1 := int(oz.bids[len(oz.bids)-2]), int(oz.bids[len(oz.bids)-1])
+	oz.coins[0] -= bid0
+	oz.coins[1] -= bid1
+	switch {
+	case bid0 > bid1:
+		oz.position++
+	case bid0 < bid1:
+		oz.position--
+	}
+	return oz
+}
+
+// Clone returns a copy of oz that shares no bids with it.
+func (oz OshiZumo) Clone() OshiZumo {
+	bids := make([]uint8, len(oz.bids))
+	copy(bids, oz.bids)
+	oz.bids = bids
+	return oz
+}
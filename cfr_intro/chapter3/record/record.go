@@ -0,0 +1,97 @@
+// Package record persists played games, for debugging, evaluation, and training learned models.
+//
+// A record file is a gob stream of a strategy.File without strategies, which describes the game,
+// followed by one match.Hand for each game.
+// Each hand holds the chance outcomes, such as the cards dealt and the dices rolled, and the actions,
+// as indices from the root, together with the probabilities of the actions at every decision and the payoffs.
+//
+// Self play games are recorded by treecfr -record after training, or by match -record with the same strategy in both seats.
+package record
+
+import (
+	"encoding/gob"
+	"io"
+
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+type Writer struct {
+	enc *gob.Encoder
+}
+
+// NewWriter writes the header of a record file of the game of f.
+func NewWriter(w io.Writer, f *strategy.File) (*Writer, error) {
+	game := *f
+	game.Strategies = nil
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(&game); err != nil {
+		return nil, errors.Wrap(err, "Encode")
+	}
+	return &Writer{enc: enc}, nil
+}
+
+func (w *Writer) Write(h match.Hand) error {
+	if err := w.enc.Encode(&h); err != nil {
+		return errors.Wrap(err, "Encode")
+	}
+	return nil
+}
+
+type Reader struct {
+	dec  *gob.Decoder
+	game *strategy.File
+	root tree.Game
+}
+
+// NewReader reads the header of a record file.
+func NewReader(r io.Reader) (*Reader, error) {
+	dec := gob.NewDecoder(r)
+	game := &strategy.File{}
+	if err := dec.Decode(game); err != nil {
+		return nil, errors.Wrap(err, "Decode")
+	}
+	root, err := game.NewGame()
+	if err != nil {
+		return nil, errors.Wrap(err, "NewGame")
+	}
+	rd := &Reader{dec: dec, game: game, root: root}
+	return rd, nil
+}
+
+// Game returns the description of the game of the records.
+func (rd *Reader) Game() *strategy.File {
+	return rd.game
+}
+
+// Root returns the root of the game of the records.
+func (rd *Reader) Root() tree.Game {
+	return rd.root
+}
+
+// Read returns the next hand, or io.EOF after the last one.
+func (rd *Reader) Read() (match.Hand, error) {
+	var h match.Hand
+	if err := rd.dec.Decode(&h); err != nil {
+		if err == io.EOF {
+			return match.Hand{}, io.EOF
+		}
+		return match.Hand{}, errors.Wrap(err, "Decode")
+	}
+	return h, nil
+}
+
+// Replay returns the next hand, and its states from the root to the end of the game.
+func (rd *Reader) Replay() (match.Hand, []tree.Game, error) {
+	h, err := rd.Read()
+	if err != nil {
+		return match.Hand{}, nil, err
+	}
+	states, err := h.Replay(rd.root)
+	if err != nil {
+		return match.Hand{}, nil, errors.Wrap(err, "Replay")
+	}
+	return h, states, nil
+}
@@ -0,0 +1,351 @@
+// Package resolve refines a blueprint strategy of two player Dudo in real time,
+// by solving the depth limited subgame rooted at the current claim history with CFR.
+//
+// Claims are public, so the subgame consists of the states at the claim history for every roll of both players,
+// weighted by the probabilities of the rolls given the history when both players follow the blueprint.
+// Beyond Depth further actions, states are valued by the blueprint.
+//
+// Solving the subgame alone is unsafe, as the opponent may have been better off reaching it with some rolls than others.
+// The safe option adds the re-solving gadget of "Solving Imperfect Information Games Using Decomposition" by Burch et al.:
+// for each of her rolls, the opponent first chooses between entering the subgame or taking her blueprint value,
+// so that the refined strategy never lets her gain over the blueprint.
+package resolve
+
+import (
+	"fmt"
+
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	// Iterations is the number of CFR iterations on each subgame.
+	Iterations int
+	// Depth is the number of actions after the current claim history beyond which the blueprint values states.
+	// The subgame is solved to the end of the game if Depth is zero.
+	Depth int
+	// Safe solves the subgame with the re-solving gadget.
+	Safe bool
+}
+
+func DefaultConfig() Config {
+	return Config{Iterations: 1000, Depth: 2, Safe: true}
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Iterations < 1 {
+		return errors.Errorf("invalid number of iterations %d", cfg.Iterations)
+	}
+	if cfg.Depth < 0 {
+		return errors.Errorf("negative depth %d", cfg.Depth)
+	}
+	return nil
+}
+
+type Resolver struct {
+	cfg       Config
+	root      games.Dudo
+	blueprint strategy.Policy
+}
+
+// NewResolver returns a resolver of the two player Dudo game of root,
+// with a blueprint such as the average strategies of a node map saved by section3.5, treecfr or vcfr.
+func NewResolver(root tree.Game, blueprint strategy.Policy, cfg Config) (*Resolver, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Validate")
+	}
+	game, ok := root.(games.Dudo)
+	if !ok {
+		return nil, errors.Errorf("unsupported game %T", root)
+	}
+	if game.NumPlayers() != 2 {
+		return nil, errors.Errorf("%d players", game.NumPlayers())
+	}
+	r := &Resolver{cfg: cfg, root: game, blueprint: blueprint}
+	return r, nil
+}
+
+// history returns the claims of game as indices into the actions of each state.
+func (r *Resolver) history(game games.Dudo) []int {
+	actions := make([]int, 0, len(game.State.History()))
+	state := r.root.Chance(0)
+	for _, a := range game.State.History() {
+		values := make([]uint16, state.ActionsLen())
+		state.(games.Dudo).Actions(values)
+		for i, v := range values {
+			if v == a {
+				actions = append(actions, i)
+				break
+			}
+		}
+		state = state.Play(actions[len(actions)-1])
+	}
+	return actions
+}
+
+// Solve solves the subgame at the claim history of game, and returns the refined strategies keyed by infoset.
+func (r *Resolver) Solve(game tree.Game) (map[string][]float64, error) {
+	g, ok := game.(games.Dudo)
+	if !ok {
+		return nil, errors.Errorf("unsupported game %T", game)
+	}
+	sg := newSubgame(r, g.CurPlayer(), r.history(g))
+	t := tree.Build(sg.root())
+	c := tree.NewCFR(t)
+	for i := 0; i < r.cfg.Iterations; i++ {
+		c.Iterate()
+	}
+	return c.AvgStrategies(), nil
+}
+
+// Probs solves the subgame at game, and returns the refined strategy of its infoset.
+func (r *Resolver) Probs(game tree.Game) []float64 {
+	strategies, err := r.Solve(game)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return r.blueprint.Probs(game)
+	}
+	s, ok := strategies[game.Infoset()]
+	if !ok {
+		return r.blueprint.Probs(game)
+	}
+	return s
+}
+
+// subgame holds what is shared by the states of a subgame.
+type subgame struct {
+	r *Resolver
+	// player is the re-solving player, who is to act at the root of the subgame.
+	player  int
+	history []int
+	rollLen [2]int
+	// ranges are the probabilities of the rolls of each player given the history, when both follow the blueprint.
+	ranges [2][]float64
+	// oppValues are the blueprint values of the opponent for each of her rolls, which she may take in the gadget.
+	oppValues []float64
+}
+
+func newSubgame(r *Resolver, player int, history []int) *subgame {
+	sg := &subgame{r: r, player: player, history: history}
+	for p := range sg.rollLen {
+		sg.rollLen[p] = r.root.State.RollLen(p)
+	}
+	for p := range sg.ranges {
+		sg.ranges[p] = sg.playerRange(p)
+	}
+
+	if r.cfg.Safe {
+		opp := 1 - player
+		sg.oppValues = make([]float64, sg.rollLen[opp])
+		for ro := range sg.oppValues {
+			for rp, prob := range sg.ranges[player] {
+				if prob == 0 {
+					continue
+				}
+				var rolls [2]int
+				rolls[player], rolls[opp] = rp, ro
+				sg.oppValues[ro] += prob * match.Value(sg.at(rolls), r.blueprint)[opp]
+			}
+		}
+	}
+	return sg
+}
+
+// at returns the state at the history with rolls.
+func (sg *subgame) at(rolls [2]int) tree.Game {
+	game := sg.r.root.Chance(rolls[0] + rolls[1]*sg.rollLen[0])
+	for _, aIdx := range sg.history {
+		game = game.Play(aIdx)
+	}
+	return game
+}
+
+// playerRange returns the probabilities of the rolls of player given the history under the blueprint.
+func (sg *subgame) playerRange(player int) []float64 {
+	probs := make([]float64, sg.rollLen[player])
+	var sum float64
+	for roll := range probs {
+		var rolls [2]int
+		rolls[player] = roll
+		game := sg.r.root.Chance(rolls[0] + rolls[1]*sg.rollLen[0])
+		prob := sg.r.root.State.RollProb(player, roll)
+		for _, aIdx := range sg.history {
+			if game.CurPlayer() == player {
+				prob *= sg.r.blueprint.Probs(game)[aIdx]
+			}
+			game = game.Play(aIdx)
+		}
+		probs[roll] = prob
+		sum += prob
+	}
+
+	if sum == 0 {
+		// The blueprint never plays the history, so fall back to the chance probabilities.
+		for roll := range probs {
+			probs[roll] = sg.r.root.State.RollProb(player, roll)
+			sum += probs[roll]
+		}
+	}
+	for roll := range probs {
+		probs[roll] /= sum
+	}
+	return probs
+}
+
+func (sg *subgame) root() *state {
+	if sg.r.cfg.Safe {
+		return &state{sg: sg, stage: dealOpponent}
+	}
+	return &state{sg: sg, stage: dealBoth}
+}
+
+type stage int
+
+const (
+	// dealBoth deals the rolls of both players in the unsafe subgame.
+	dealBoth stage = iota
+	// dealOpponent, gadget and dealPlayer make the re-solving gadget,
+	// in which the opponent is dealt, chooses between her blueprint value and the subgame, and the player is dealt.
+	dealOpponent
+	gadget
+	terminated
+	dealPlayer
+	play
+)
+
+const (
+	terminate = 0
+	follow    = 1
+)
+
+// state is a state of a subgame, which implements tree.Game.
+type state struct {
+	sg    *subgame
+	stage stage
+	rolls [2]int
+	// game is the Dudo state while playing, and depth is the number of actions taken in the subgame.
+	game  tree.Game
+	depth int
+}
+
+func (s *state) opponent() int {
+	return 1 - s.sg.player
+}
+
+func (s *state) NumPlayers() int {
+	return 2
+}
+
+func (s *state) IsTerminal() bool {
+	switch s.stage {
+	case terminated:
+		return true
+	case play:
+		return s.game.IsTerminal() || (s.sg.r.cfg.Depth > 0 && s.depth >= s.sg.r.cfg.Depth)
+	}
+	return false
+}
+
+func (s *state) Payoff(outPayoff []float64) {
+	if s.stage == terminated {
+		v := s.sg.oppValues[s.rolls[s.opponent()]]
+		outPayoff[s.opponent()] = v
+		outPayoff[s.sg.player] = -v
+		return
+	}
+	if s.game.IsTerminal() {
+		s.game.Payoff(outPayoff)
+		return
+	}
+	copy(outPayoff, match.Value(s.game, s.sg.r.blueprint))
+}
+
+func (s *state) IsChanceNode() bool {
+	switch s.stage {
+	case dealBoth, dealOpponent, dealPlayer:
+		return true
+	}
+	return false
+}
+
+func (s *state) ChanceLen() int {
+	switch s.stage {
+	case dealBoth:
+		return s.sg.rollLen[0] * s.sg.rollLen[1]
+	case dealOpponent:
+		return s.sg.rollLen[s.opponent()]
+	case dealPlayer:
+		return s.sg.rollLen[s.sg.player]
+	}
+	return 0
+}
+
+func (s *state) ChanceProb(outcome int) float64 {
+	switch s.stage {
+	case dealBoth:
+		return s.sg.ranges[0][outcome%s.sg.rollLen[0]] * s.sg.ranges[1][outcome/s.sg.rollLen[0]]
+	case dealOpponent:
+		return s.sg.ranges[s.opponent()][outcome]
+	case dealPlayer:
+		return s.sg.ranges[s.sg.player][outcome]
+	}
+	return 0
+}
+
+func (s *state) Chance(outcome int) tree.Game {
+	child := *s
+	switch s.stage {
+	case dealBoth:
+		child.rolls = [2]int{outcome % s.sg.rollLen[0], outcome / s.sg.rollLen[0]}
+		child.stage = play
+		child.game = s.sg.at(child.rolls)
+	case dealOpponent:
+		child.rolls[s.opponent()] = outcome
+		child.stage = gadget
+	case dealPlayer:
+		child.rolls[s.sg.player] = outcome
+		child.stage = play
+		child.game = s.sg.at(child.rolls)
+	}
+	return &child
+}
+
+func (s *state) CurPlayer() int {
+	if s.stage == gadget {
+		return s.opponent()
+	}
+	return s.game.CurPlayer()
+}
+
+func (s *state) ActionsLen() int {
+	if s.stage == gadget {
+		return 2
+	}
+	return s.game.ActionsLen()
+}
+
+func (s *state) Play(aIdx int) tree.Game {
+	child := *s
+	if s.stage == gadget {
+		if aIdx == terminate {
+			child.stage = terminated
+		} else {
+			child.stage = dealPlayer
+		}
+		return &child
+	}
+	child.game = s.game.Play(aIdx)
+	child.depth++
+	return &child
+}
+
+func (s *state) Infoset() string {
+	if s.stage == gadget {
+		return fmt.Sprintf("gadget|%d", s.rolls[s.opponent()])
+	}
+	return s.game.Infoset()
+}
@@ -0,0 +1,232 @@
+// Package rl exposes the games of the CFR experiments as reinforcement learning environments, for self-play.
+//
+// An environment deals the chance nodes itself, so agents only see the decisions of the game.
+// Observations and legal action masks have fixed sizes given by package features,
+// so that batches of them can be fed as [][]float32 tensors to networks created with util/tensorflow.
+// Actions are the action slots of package features.
+package rl
+
+import (
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/distill"
+	"github.com/fumin/bangbang/cfr/chapter3/features"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// Env is a multi-player environment in which players take turns.
+type Env interface {
+	NumPlayers() int
+	// ObservationSize returns the number of elements of an observation.
+	ObservationSize() int
+	// NumActions returns the number of action slots.
+	NumActions() int
+
+	// Reset starts a new episode.
+	Reset() error
+	// Done tells whether the episode has ended, which it has before the first Reset.
+	Done() bool
+	// CurPlayer returns the player to act.
+	CurPlayer() int
+	// Observe writes what player sees to out, which has ObservationSize elements, or zeros once the episode has ended.
+	Observe(player int, out []float32)
+	// LegalActions writes 1 to the slots of the legal actions and 0 elsewhere to out, which has NumActions elements,
+	// or zeros once the episode has ended.
+	LegalActions(out []float32)
+	// Step plays the action of the current player, and returns the reward of each player.
+	Step(action int) ([]float64, error)
+}
+
+// Game is an environment of a Kuhn, Dudo or matrix game.
+// Its state is nil, and its episode done, until the first Reset.
+type Game struct {
+	root  tree.Game
+	enc   *features.Encoder
+	src   chance.Source
+	state tree.Game
+}
+
+// NewGame returns an environment of the game of root, whose chance outcomes are provided by src.
+// The first episode starts with Reset, so that it does not consume the chance outcomes of an episode that is never played.
+func NewGame(root tree.Game, src chance.Source) (*Game, error) {
+	enc, err := features.NewEncoder(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewEncoder")
+	}
+	g := &Game{root: root, enc: enc, src: src}
+	return g, nil
+}
+
+func (g *Game) NumPlayers() int {
+	return g.root.NumPlayers()
+}
+
+func (g *Game) ObservationSize() int {
+	return g.enc.Size()
+}
+
+func (g *Game) NumActions() int {
+	return g.enc.NumActions()
+}
+
+// State returns the current state, which is useful for evaluating agents with the tools of CFR, or nil before the first Reset.
+func (g *Game) State() tree.Game {
+	return g.state
+}
+
+func (g *Game) Reset() error {
+	return g.advance(g.root)
+}
+
+// advance deals the chance nodes from game onwards, until a decision or the end of the game.
+func (g *Game) advance(game tree.Game) error {
+	for !game.IsTerminal() && game.IsChanceNode() {
+		o, err := g.src.Outcome(game)
+		if err != nil {
+			return errors.Wrap(err, "Outcome")
+		}
+		game = game.Chance(o)
+	}
+	g.state = game
+	return nil
+}
+
+func (g *Game) Done() bool {
+	return g.state == nil || g.state.IsTerminal()
+}
+
+// CurPlayer returns the player to act, or tree.Terminal before the first Reset.
+func (g *Game) CurPlayer() int {
+	if g.state == nil {
+		return tree.Terminal
+	}
+	return g.state.CurPlayer()
+}
+
+func (g *Game) Observe(player int, out []float32) {
+	if g.Done() {
+		for i := range out {
+			out[i] = 0
+		}
+		return
+	}
+	g.enc.EncodePlayer(g.state, player, out)
+}
+
+func (g *Game) LegalActions(out []float32) {
+	if g.Done() {
+		for i := range out {
+			out[i] = 0
+		}
+		return
+	}
+	g.enc.Mask(g.state, out)
+}
+
+func (g *Game) Step(action int) ([]float64, error) {
+	if g.state == nil {
+		return nil, errors.Errorf("episode not started by Reset")
+	}
+	if g.Done() {
+		return nil, errors.Errorf("episode ended")
+	}
+	slots := make([]int, g.state.ActionsLen())
+	g.enc.Actions(g.state, slots)
+	aIdx := -1
+	for i, s := range slots {
+		if s == action {
+			aIdx = i
+		}
+	}
+	if aIdx == -1 {
+		return nil, errors.Errorf("illegal action %d, legal %v", action, slots)
+	}
+
+	if err := g.advance(g.state.Play(aIdx)); err != nil {
+		return nil, errors.Wrap(err, "advance")
+	}
+	rewards := make([]float64, g.NumPlayers())
+	if g.Done() {
+		g.state.Payoff(rewards)
+	}
+	return rewards, nil
+}
+
+// Agent chooses actions from observations, such as a policy network.
+type Agent interface {
+	// Act returns the action slot to play, given an observation and a mask of the legal actions.
+	Act(observation, legal []float32) (int, error)
+}
+
+// Random plays uniformly random legal actions.
+type Random struct{}
+
+func (Random) Act(observation, legal []float32) (int, error) {
+	return sample(legal, legal), nil
+}
+
+// NetAgent samples actions from the probabilities of a policy network, such as the one trained by cmd/distill.
+type NetAgent struct {
+	Net distill.Net
+}
+
+func (a NetAgent) Act(observation, legal []float32) (int, error) {
+	probs, err := a.Net.Predict([][]float32{observation}, [][]float32{legal})
+	if err != nil {
+		return -1, errors.Wrap(err, "Predict")
+	}
+	return sample(probs[0], legal), nil
+}
+
+// sample returns a legal action slot sampled from probs, renormalized over the legal slots.
+func sample(probs, legal []float32) int {
+	slots := make([]int, 0, len(legal))
+	weights := make([]float64, 0, len(legal))
+	var sum float64
+	for s, l := range legal {
+		if l > 0 {
+			slots = append(slots, s)
+			weights = append(weights, float64(probs[s]))
+			sum += float64(probs[s])
+		}
+	}
+	for i := range weights {
+		if sum > 0 {
+			weights[i] /= sum
+		} else {
+			weights[i] = 1 / float64(len(weights))
+		}
+	}
+	return slots[strategy.SampleAction(weights)]
+}
+
+// Episode plays an episode in which agents[p] acts for player p, and returns the total reward of each player.
+func Episode(env Env, agents []Agent) ([]float64, error) {
+	if len(agents) != env.NumPlayers() {
+		return nil, errors.Errorf("%d agents for %d players", len(agents), env.NumPlayers())
+	}
+	if err := env.Reset(); err != nil {
+		return nil, errors.Wrap(err, "Reset")
+	}
+	observation := make([]float32, env.ObservationSize())
+	legal := make([]float32, env.NumActions())
+	returns := make([]float64, env.NumPlayers())
+	for !env.Done() {
+		player := env.CurPlayer()
+		env.Observe(player, observation)
+		env.LegalActions(legal)
+		action, err := agents[player].Act(observation, legal)
+		if err != nil {
+			return nil, errors.Wrapf(err, "player %d", player)
+		}
+		rewards, err := env.Step(action)
+		if err != nil {
+			return nil, errors.Wrapf(err, "player %d", player)
+		}
+		for p, r := range rewards {
+			returns[p] += r
+		}
+	}
+	return returns, nil
+}
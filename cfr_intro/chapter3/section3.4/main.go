@@ -4,51 +4,28 @@ package main
 import (
 	"bufio"
 	"flag"
-	"fmt"
-	"math/rand"
 	"os"
 	"sort"
-	"strconv"
 
 	"github.com/fumin/bangbang/cfr/chapter3"
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
 	"github.com/golang/glog"
-)
-
-const (
-	Pass       = 0
-	Bet        = 1
-	NumActions = 2
+	"github.com/pkg/errors"
 )
 
 var (
-// cardsGetter = NewCardsGetter()
+	numCards   = flag.Int("cards", 3, "number of cards in the deck")
+	numPlayers = flag.Int("players", 2, "number of players")
+	ante       = flag.Int("ante", 1, "ante of each player")
+	betSize    = flag.Int("bet", 1, "size of bets and raises")
+	maxBets    = flag.Int("max_bets", 1, "number of bets and raises allowed")
+	iterations = flag.Int("iterations", 1000000, "number of CFR iterations, fewer if the trace of -deals ends earlier")
+	seed       = flag.Int64("seed", 1, "seed of the random deals")
+	dealsPath  = flag.String("deals", "", "path of a trace of deals to train on instead of random deals, with one card order such as \"312\" per line as in the rand.txt of KuhnTrainer.java")
+	recordPath = flag.String("record_deals", "", "path to record the deals to, in the format of -deals")
 )
 
-type CardsGetter struct {
-	scanner *bufio.Scanner
-}
-
-func NewCardsGetter() *CardsGetter {
-	f, err := os.Open("rand.txt")
-	if err != nil {
-		glog.Fatalf("%+v", err)
-	}
-	cg := &CardsGetter{
-		scanner: bufio.NewScanner(f),
-	}
-	return cg
-}
-
-func (cg *CardsGetter) get() []int {
-	cg.scanner.Scan()
-	line := cg.scanner.Text()
-	cards := []int{1, 2, 3}
-	cards[0], _ = strconv.Atoi(line[0:1])
-	cards[1], _ = strconv.Atoi(line[1:2])
-	cards[2], _ = strconv.Atoi(line[2:3])
-	return cards
-}
-
 type Agent struct {
 	nodeMap map[string]*chapter3.Node
 }
@@ -60,21 +37,37 @@ func NewAgent() *Agent {
 	return agent
 }
 
-func train(agent *Agent, iterations int) {
-	cards := []int{1, 2, 3}
-	var util float64 = 0
-	for i := 0; i < iterations; i++ {
+func train(agent *Agent, cfg kuhn.Config, iterations int, src chance.Source) error {
+	util := make([]float64, cfg.NumPlayers)
+	i := 0
+	for ; i < iterations; i++ {
+		game, err := kuhn.NewKuhnConfig(cfg)
+		if err != nil {
+			return errors.Wrap(err, "NewKuhnConfig")
+		}
 		// Shuffle cards
-		for c1 := len(cards) - 1; c1 > 0; c1-- {
-			c2 := rand.Intn(c1 + 1)
-			cards[c1], cards[c2] = cards[c2], cards[c1]
+		o, err := src.Outcome(game)
+		if errors.Cause(err) == chance.ErrEnd {
+			glog.Infof("deals ended after %d iterations", i)
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "iteration %d", i)
 		}
-		// cards = cardsGetter.get()
+		game.Chance(o)
 
-		util += cfr(agent, cards, "", 1, 1)
+		probs := make([]float64, cfg.NumPlayers)
+		for p := range probs {
+			probs[p] = 1
+		}
+		for p, u := range cfr(agent, game, probs) {
+			util[p] += u
+		}
 	}
 
-	glog.Infof("Average game value %f", util/float64(iterations))
+	for p, u := range util {
+		glog.Infof("Average game value of player %d: %f", p, u/float64(i))
+	}
 
 	// Sort infoSets and print them
 	infoSets := make([]string, 0, len(agent.nodeMap))
@@ -86,100 +79,116 @@ func train(agent *Agent, iterations int) {
 		n := agent.nodeMap[is]
 		glog.Infof("%4s: %+v", n.InfoSet, n.AvgStrategy())
 	}
+	return nil
 }
 
-func terminalPayoff(cards []int, history string, plays, player, opponent int) (float64, bool) {
-	if plays > 1 {
-		terminalPass := (history[plays-1] == 'p')
-		doubleBet := history[plays-2:plays] == "bb"
-		isPlayerCardHigher := cards[player] > cards[opponent]
-		if terminalPass {
-			if history == "pp" {
-				if isPlayerCardHigher {
-					return 1, true
-				} else {
-					return -1, true
-				}
-			} else {
-				return 1, true
-			}
-		} else if doubleBet {
-			if isPlayerCardHigher {
-				return 2, true
-			} else {
-				return -2, true
-			}
-		}
-	}
-	return -1, false
-}
-
-func cfr(agent *Agent, cards []int, history string, p0, p1 float64) float64 {
-	plays := len(history)
-	player := plays % 2
-	opponent := 1 - player
+// cfr returns the utilities of all players.
+// Payoffs and infosets come from the rules of game, so that the same code trains all variants of Kuhn.
+func cfr(agent *Agent, game kuhn.Kuhn, probs []float64) []float64 {
+	numPlayers := game.NumPlayers()
 
 	// Return payoff for terminal states.
-	payoff, ok := terminalPayoff(cards, history, plays, player, opponent)
-	if ok {
+	if game.IsTerminal() {
+		payoff := make([]float64, numPlayers)
+		game.Payoff(payoff)
 		return payoff
 	}
 
-	infoSet := fmt.Sprintf("%d%s", cards[player], history)
+	player := game.CurPlayer()
+	infoSetBuf := make([]uint8, game.InfosetLen())
+	game.Infoset(infoSetBuf)
+	infoSet := string(infoSetBuf)
+	actions := make([]uint8, game.ActionsLen())
+	game.Actions(actions)
 	// Get information set node or create it if nonexistant.
 	node, ok := agent.nodeMap[infoSet]
 	if !ok {
-		node = chapter3.NewNode(NumActions)
+		node = chapter3.NewNode(len(actions))
 		node.InfoSet = infoSet
 		agent.nodeMap[infoSet] = node
 	}
 
 	// For each action, recursively call cfr with additional history and probability.
 	strategy := node.GetStrategy()
-	var realizationWeight float64 = -1
-	if player == 0 {
-		realizationWeight = p0
-	} else {
-		realizationWeight = p1
-	}
-	node.AccStrategy(strategy, realizationWeight)
-	util := make([]float64, NumActions)
-	var nodeUtil float64
-	for a := 0; a < NumActions; a++ {
-		actStr := ""
-		if a == 0 {
-			actStr = "p"
-		} else {
-			actStr = "b"
+	node.AccStrategy(strategy, probs[player])
+	util := make([]float64, len(actions))
+	nodeUtil := make([]float64, numPlayers)
+	for a, action := range actions {
+		nextProbs := make([]float64, numPlayers)
+		copy(nextProbs, probs)
+		nextProbs[player] *= strategy[a]
+
+		stUtil := cfr(agent, game.Play(action), nextProbs)
+		util[a] = stUtil[player]
+		for p, u := range stUtil {
+			nodeUtil[p] += strategy[a] * u
 		}
-		nextHistory := fmt.Sprintf("%s%s", history, actStr)
-
-		if player == 0 {
-			util[a] = -cfr(agent, cards, nextHistory, p0*strategy[a], p1)
-		} else {
-			util[a] = -cfr(agent, cards, nextHistory, p0, p1*strategy[a])
-		}
-		nodeUtil += strategy[a] * util[a]
 	}
 
 	// For each action, compute and accumulate counterfactual regret.
-	for a, aUtil := range util {
-		regret := aUtil - nodeUtil
-		if player == 0 {
-			node.RegretSum[a] += p1 * regret
-		} else {
-			node.RegretSum[a] += p0 * regret
+	var probNegI float64 = 1
+	for p, prb := range probs {
+		if p != player {
+			probNegI *= prb
 		}
 	}
+	for a, aUtil := range util {
+		regret := aUtil - nodeUtil[player]
+		node.RegretSum[a] += probNegI * regret
+	}
 
 	return nodeUtil
 }
 
+// run trains on the deals of the flags, and closes the files of the deals before returning, even on errors.
+func run(cfg kuhn.Config) error {
+	game, err := kuhn.NewKuhnConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "NewKuhnConfig")
+	}
+	var src chance.Source = chance.NewLive(*seed)
+	if *dealsPath != "" {
+		f, err := os.Open(*dealsPath)
+		if err != nil {
+			return errors.Wrap(err, "os.Open")
+		}
+		defer f.Close()
+		src = chance.NewTrace(f, game.ParseDeal)
+	}
+	var w *bufio.Writer
+	if *recordPath != "" {
+		f, err := os.Create(*recordPath)
+		if err != nil {
+			return errors.Wrap(err, "os.Create")
+		}
+		defer f.Close()
+		w = bufio.NewWriter(f)
+		src = chance.NewRecorder(src, w, game.FormatDeal)
+	}
+
+	agent := NewAgent()
+	trainErr := train(agent, cfg, *iterations, src)
+	// Flush the deals recorded so far even if training failed.
+	if w != nil {
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "Flush")
+		}
+	}
+	return trainErr
+}
+
 func main() {
 	flag.Set("logtostderr", "true")
 	flag.Parse()
 
-	agent := NewAgent()
-	iterations := 1000000
-	train(agent, iterations)
+	cfg := kuhn.Config{
+		NumCards:   *numCards,
+		NumPlayers: *numPlayers,
+		Ante:       *ante,
+		BetSize:    *betSize,
+		MaxBets:    *maxBets,
+	}
+	if err := run(cfg); err != nil {
+		glog.Fatalf("%+v", err)
+	}
 }
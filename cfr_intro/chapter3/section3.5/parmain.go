@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"fmt"
-	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"runtime"
@@ -11,30 +10,19 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/fumin/bangbang/cfr/chapter3/chance"
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
 	"github.com/golang/glog"
 )
 
-const (
-	invalidDice uint8 = 255
+var (
+	playerDices = flag.String("dices", "1,1", "comma separated number of dices of each player")
+	iterations  = flag.Int("iterations", 1000000, "number of CFR iterations")
+	seed        = flag.Int64("seed", 1, "seed of the rolls")
+	savePath    = flag.String("save", "", "path to save the average strategies of the node map to, as a blueprint for resolving")
 )
 
-// http://mlanctot.info/files/675proj/report.pdf
-
-// http://cs.gettysburg.edu/~tneller/games/rules/dudo.pdf
-func strength(n, r, diceFaces, totalNumDices int) int {
-	if r != 1 {
-		return (diceFaces-1)*n + (n / 2) - (diceFaces - r) - 1
-	}
-
-	if n <= (totalNumDices / 2) {
-		return (2 * diceFaces * n) - n - diceFaces
-	}
-	if n == (totalNumDices/2 + 1) {
-		return (diceFaces-1)*totalNumDices + n - 1
-	}
-	panic(fmt.Sprintf("with r == 1, n %d cannot be larger than %d", n, totalNumDices/2+1))
-}
-
 type Node struct {
 	InfoSet     string
 	RegretSum   []float64
@@ -98,173 +86,7 @@ func (node *Node) AvgStrategy() []float64 {
 	return avgStrat
 }
 
-type Claim struct {
-	Num  uint8
-	Rank uint8
-}
-
-type Dudo struct {
-	diceFaces uint8
-	claims    []Claim
-
-	history []uint8
-	dices   [][]uint8
-}
-
-func NewDudo(diceFaces uint8, numDices []uint8) Dudo {
-	dudo := Dudo{
-		diceFaces: diceFaces,
-		history:   make([]uint8, 0),
-	}
-
-	// Enumerate the claims.
-	totalNumDices := 0
-	for _, playerNumDices := range numDices {
-		totalNumDices += int(playerNumDices)
-	}
-	dudo.claims = make([]Claim, 0)
-	for n := 1; n <= totalNumDices; n++ {
-		if n%2 == 0 {
-			clm := Claim{Num: uint8(n / 2), Rank: 1}
-			dudo.claims = append(dudo.claims, clm)
-		}
-		for r := 2; r <= int(diceFaces); r++ {
-			clm := Claim{Num: uint8(n), Rank: uint8(r)}
-			dudo.claims = append(dudo.claims, clm)
-		}
-	}
-	clm := Claim{Num: uint8(totalNumDices/2 + 1), Rank: 1}
-	dudo.claims = append(dudo.claims, clm)
-	if len(dudo.claims) > 254 {
-		panic(fmt.Sprintf("number of claims %d larger than 254", dudo.claims))
-	}
-
-	// Initialize all players' dices.
-	numPlayers := len(numDices)
-	dudo.dices = make([][]uint8, numPlayers)
-	for p := 0; p < numPlayers; p++ {
-		dudo.dices[p] = make([]uint8, numDices[p])
-		for i := 0; i < len(dudo.dices[p]); i++ {
-			dudo.dices[p][i] = invalidDice
-		}
-	}
-
-	return dudo
-}
-
-func (dudo Dudo) CurPlayer() int {
-	numPlayers := len(dudo.dices)
-	player := len(dudo.history) % numPlayers
-	return player
-}
-
-func (dudo Dudo) InfosetLen() int {
-	playerDices := dudo.dices[dudo.CurPlayer()]
-	size := len(playerDices)
-	size += 1 // for the separator '|'
-	size += len(dudo.history)
-	return size
-}
-
-func (dudo Dudo) Infoset(outInfoset []uint8) {
-	cursor := 0
-
-	playerDices := dudo.dices[dudo.CurPlayer()]
-	copy(outInfoset[cursor:], playerDices)
-	cursor += len(playerDices)
-
-	outInfoset[cursor] = '|'
-	cursor += 1
-
-	copy(outInfoset[cursor:], dudo.history)
-}
-
-func (dudo Dudo) IsTerminal() bool {
-	if len(dudo.history) == 0 {
-		return false
-	}
-	lastAct := dudo.history[len(dudo.history)-1]
-	return int(lastAct) == len(dudo.claims)
-}
-
-func (dudo Dudo) Payoff(outPayoff []float64) {
-	// Find the player who challenged Dudo.
-	numPlayers := len(dudo.dices)
-	dudoIdx := len(dudo.history) - 1
-	dudoPlayer := dudoIdx % numPlayers
-
-	// Find the player whose claim was challenged.
-	claimIdx := len(dudo.history) - 2
-	claimPlayer := claimIdx % numPlayers
-	claimID := dudo.history[claimIdx]
-	claim := dudo.claims[claimID]
-
-	// Count the actual total number of dices that have the claimed rank.
-	actual := 0
-	for _, playerDices := range dudo.dices {
-		for _, d := range playerDices {
-			if d == 1 || d == claim.Rank {
-				actual++
-			}
-		}
-	}
-
-	// If actual rank count is equal to claim,
-	// the player who makes the claim wins, and everyone else pays her one dice.
-	if actual == int(claim.Num) {
-		for p := 0; p < numPlayers; p++ {
-			if p == claimPlayer {
-				outPayoff[p] = float64(numPlayers) - 1
-			} else {
-				outPayoff[p] = -1
-			}
-		}
-		return
-	}
-
-	outPayoff[claimPlayer] = float64(actual - int(claim.Num))
-	outPayoff[dudoPlayer] = float64(int(claim.Num) - actual)
-}
-
-func (dudo Dudo) IsChanceNode() bool {
-	player := dudo.CurPlayer()
-	firstDice := 0
-	return dudo.dices[player][firstDice] == invalidDice
-}
-
-func (dudo Dudo) SampleChance() {
-	player := dudo.CurPlayer()
-	numDices := len(dudo.dices[player])
-	for i := 0; i < numDices; i++ {
-		dudo.dices[player][i] = uint8(rand.Intn(int(dudo.diceFaces)) + 1)
-	}
-}
-
-func (dudo Dudo) ActionsLen() int {
-	if len(dudo.history) == 0 {
-		return len(dudo.claims)
-	}
-
-	lastClaim := dudo.history[len(dudo.history)-1]
-	dudoAct := len(dudo.claims)
-	return dudoAct - int(lastClaim)
-}
-
-func (dudo Dudo) Actions(outActions []uint8) {
-	if len(dudo.history) == 0 {
-		for i := 0; i < len(outActions); i++ {
-			outActions[i] = uint8(i)
-		}
-		return
-	}
-
-	lastClaim := int(dudo.history[len(dudo.history)-1])
-	for i := 0; i < len(outActions); i++ {
-		outActions[i] = uint8(i + lastClaim + 1)
-	}
-}
-
-func getInfosetNode(dudo Dudo, nodeMap map[string]*Node, isBuf []uint8) *Node {
+func getInfosetNode(dudo dudo.Dudo, nodeMap map[string]*Node, isBuf []uint8) *Node {
 	dudo.Infoset(isBuf)
 	node, ok := nodeMap[string(isBuf)]
 	if !ok {
@@ -347,30 +169,70 @@ func (stk *Uint8Stack) Grow(size int) []uint8 {
 	return res
 }
 
+type Uint16Stack struct {
+	buf []uint16
+	cur int
+}
+
+func NewUint16Stack() *Uint16Stack {
+	stk := &Uint16Stack{
+		buf: make([]uint16, 1024*1024),
+	}
+	return stk
+}
+
+func (stk *Uint16Stack) Enter() int {
+	return stk.cur
+}
+
+func (stk *Uint16Stack) Leave(cur int) {
+	stk.cur = cur
+}
+
+func (stk *Uint16Stack) Grow(size int) []uint16 {
+	cur := stk.cur
+	stk.cur += size
+	if stk.cur > len(stk.buf) {
+		newBuf := make([]uint16, stk.cur*2)
+		copy(newBuf, stk.buf)
+		stk.buf = newBuf
+	}
+
+	res := stk.buf[cur:stk.cur]
+	for i := range res {
+		res[i] = 0
+	}
+	return res
+}
+
 type Stack struct {
-	f64Stk   *F64Stack
-	uint8Stk *Uint8Stack
+	f64Stk    *F64Stack
+	uint8Stk  *Uint8Stack
+	uint16Stk *Uint16Stack
 }
 
 func NewStack() *Stack {
 	stk := &Stack{
-		f64Stk:   NewF64Stack(),
-		uint8Stk: NewUint8Stack(),
+		f64Stk:    NewF64Stack(),
+		uint8Stk:  NewUint8Stack(),
+		uint16Stk: NewUint16Stack(),
 	}
 	return stk
 }
 
-func (stk *Stack) Enter() [2]int {
-	cursor := [2]int{
+func (stk *Stack) Enter() [3]int {
+	cursor := [3]int{
 		stk.f64Stk.Enter(),
 		stk.uint8Stk.Enter(),
+		stk.uint16Stk.Enter(),
 	}
 	return cursor
 }
 
-func (stk *Stack) Leave(cursor [2]int) {
+func (stk *Stack) Leave(cursor [3]int) {
 	stk.f64Stk.Leave(cursor[0])
 	stk.uint8Stk.Leave(cursor[1])
+	stk.uint16Stk.Leave(cursor[2])
 }
 
 func (stk *Stack) GrowF64(size int) []float64 {
@@ -381,8 +243,12 @@ func (stk *Stack) GrowUint8(size int) []uint8 {
 	return stk.uint8Stk.Grow(size)
 }
 
-func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []float64 {
-	numPlayers := len(dudo.dices)
+func (stk *Stack) GrowUint16(size int) []uint16 {
+	return stk.uint16Stk.Grow(size)
+}
+
+func cfr(dudo dudo.Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack, src chance.Source) []float64 {
+	numPlayers := dudo.NumPlayers()
 	if dudo.IsTerminal() {
 		cursor := stack.Enter()
 		payoff := stack.GrowF64(numPlayers)
@@ -391,8 +257,10 @@ func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []f
 		return payoff
 	}
 	if dudo.IsChanceNode() {
-		dudo.SampleChance()
-		return cfr(dudo, probs, nodeMap, stack)
+		if err := dudo.SampleChance(src); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		return cfr(dudo, probs, nodeMap, stack, src)
 	}
 
 	cursor := stack.Enter()
@@ -400,7 +268,7 @@ func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []f
 	// Create buffer for the utilities for all players.
 	util := stack.GrowF64(numPlayers)
 	// Get the list of allowed actions.
-	actions := stack.GrowUint8(dudo.ActionsLen())
+	actions := stack.GrowUint16(dudo.ActionsLen())
 	dudo.Actions(actions)
 	// Create buffer for the utility for the actions of the current player.
 	actionUtil := stack.GrowF64(len(actions))
@@ -416,8 +284,7 @@ func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []f
 		actProb := strategy[aIdx]
 
 		// Create the new state in the subtree.
-		stDudo := dudo
-		stDudo.history = append(stDudo.history, a)
+		stDudo := dudo.Play(a)
 
 		// Create the history probabilities for the subtree.
 		stProbs := stack.GrowF64(len(probs))
@@ -425,7 +292,7 @@ func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []f
 		stProbs[player] *= actProb
 
 		// Calculate all players' utilities of the subtree.
-		stUtil := cfr(stDudo, stProbs, nodeMap, stack)
+		stUtil := cfr(stDudo, stProbs, nodeMap, stack, src)
 
 		actionUtil[aIdx] = stUtil[player]
 		for p, playerUtil := range util {
@@ -454,22 +321,24 @@ func cfr(dudo Dudo, probs []float64, nodeMap map[string]*Node, stack *Stack) []f
 	return util
 }
 
-func cfrpar(dudo Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*Stack) []float64 {
-	numPlayers := len(dudo.dices)
+func cfrpar(dudo dudo.Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*Stack, src chance.Source) []float64 {
+	numPlayers := dudo.NumPlayers()
 	if dudo.IsTerminal() {
 		payoff := make([]float64, numPlayers)
 		dudo.Payoff(payoff)
 		return payoff
 	}
 	if dudo.IsChanceNode() {
-		dudo.SampleChance()
-		return cfrpar(dudo, probs, nodeMaps, stacks)
+		if err := dudo.SampleChance(src); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+		return cfrpar(dudo, probs, nodeMaps, stacks, src)
 	}
 
 	// Create buffer for the utilities for all players.
 	util := make([]float64, numPlayers)
 	// Get the list of allowed actions.
-	actions := make([]uint8, dudo.ActionsLen())
+	actions := make([]uint16, dudo.ActionsLen())
 	dudo.Actions(actions)
 	// Create buffer for the utility for the actions of the current player.
 	actionUtil := make([]float64, len(actions))
@@ -481,7 +350,7 @@ func cfrpar(dudo Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*S
 
 	type Act struct {
 		idx    int
-		action uint8
+		action uint16
 	}
 	workerActions := make([][]Act, len(nodeMaps))
 	for i, a := range actions {
@@ -513,8 +382,7 @@ func cfrpar(dudo Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*S
 				actProb := strategy[aIdx]
 
 				// Create the new state in the subtree.
-				stDudo := dudo
-				stDudo.history = append(stDudo.history, a)
+				stDudo := dudo.Play(a)
 
 				// Create the history probabilities for the subtree.
 				stProbs := stack.GrowF64(len(probs))
@@ -522,7 +390,7 @@ func cfrpar(dudo Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*S
 				stProbs[player] *= actProb
 
 				// Calculate all players' utilities of the subtree.
-				stUtil := cfr(stDudo, stProbs, nodeMap, stack)
+				stUtil := cfr(stDudo, stProbs, nodeMap, stack, src)
 
 				ur := UtilRes{aIdx: aIdx, stUtil: make([]float64, len(stUtil))}
 				copy(ur.stUtil, stUtil)
@@ -564,24 +432,6 @@ func cfrpar(dudo Dudo, probs []float64, nodeMaps []map[string]*Node, stacks []*S
 	return util
 }
 
-func fmtInfoset(infoset string) string {
-	splitted := strings.Split(infoset, "|")
-
-	rawDices := []uint8(splitted[0])
-	dices := make([]uint8, 0, len(rawDices))
-	for _, d := range rawDices {
-		dices = append(dices, d+'0')
-	}
-
-	rawHist := []uint8(splitted[1])
-	history := make([]uint8, 0, len(rawHist))
-	for _, h := range rawHist {
-		history = append(history, h+'a')
-	}
-
-	return string(dices) + "|" + string(history)
-}
-
 func fmtFloatSlice(fs []float64, precision int) string {
 	ss := make([]string, 0, len(fs))
 	for _, f := range fs {
@@ -591,16 +441,32 @@ func fmtFloatSlice(fs []float64, precision int) string {
 	return fmt.Sprintf("[%s]", strings.Join(ss, " "))
 }
 
-func printNodeMap(nodeMap map[string]*Node, numPlayers int) {
+// saveNodeMap saves the average strategies of nodeMap as a strategy file.
+func saveNodeMap(path string, diceFaces uint8, numDices []uint8, nodeMap map[string]*Node) error {
+	file := &strategy.File{
+		Game:       strategy.Dudo,
+		DudoRules:  dudo.DefaultRules(diceFaces),
+		NumDices:   numDices,
+		Strategies: make(map[string][]float64, len(nodeMap)),
+	}
+	for infoset, node := range nodeMap {
+		file.Strategies[infoset] = node.AvgStrategy()
+	}
+	return strategy.Save(path, file)
+}
+
+func printNodeMap(game dudo.Dudo, nodeMap map[string]*Node) {
 	// Create the infosets for each player.
+	numPlayers := game.NumPlayers()
 	playerInfoset := make([][]string, numPlayers)
 	for p := 0; p < numPlayers; p++ {
 		playerInfoset[p] = make([]string, 0)
 	}
 	for infoset, _ := range nodeMap {
-		splitted := strings.Split(infoset, "|")
-		history := []uint8(splitted[1])
-		player := len(history) % numPlayers
+		_, player, _, err := game.ParseInfoset(infoset)
+		if err != nil {
+			glog.Fatalf("%+v", err)
+		}
 
 		playerInfoset[player] = append(playerInfoset[player], infoset)
 	}
@@ -614,7 +480,7 @@ func printNodeMap(nodeMap map[string]*Node, numPlayers int) {
 			n := nodeMap[is]
 			avgStrat := n.AvgStrategy()
 
-			fmt.Printf("%6s: %s\n", fmtInfoset(n.InfoSet), fmtFloatSlice(avgStrat, 2))
+			fmt.Printf("%6s: %s\n", game.FormatInfoset(n.InfoSet), fmtFloatSlice(avgStrat, 2))
 		}
 		fmt.Printf("\n")
 	}
@@ -666,7 +532,10 @@ func main() {
 		glog.Fatal(http.ListenAndServe("localhost:6061", nil))
 	}()
 
-	numDices := []uint8{1, 1}
+	numDices, err := dudo.ParseNumDices(*playerDices)
+	if err != nil {
+		glog.Fatalf("%+v", err)
+	}
 	// Create the initial subtree probabilities, which are ones.
 	numPlayers := len(numDices)
 	probs := make([]float64, numPlayers)
@@ -676,8 +545,8 @@ func main() {
 
 	var diceFaces uint8 = 6
 
-	dudo := NewDudo(diceFaces, numDices)
-	glog.Infof("Claims: %+v", dudo.claims)
+	game := dudo.NewDudo(diceFaces, numDices)
+	glog.Infof("Claims: %+v", game.Claims())
 
 	// Create resources for our workers.
 	numWorkers := runtime.NumCPU()
@@ -689,12 +558,12 @@ func main() {
 	}
 
 	// Train our algorithm.
-	iterations := 1000000
-	utilLogger := NewAvgLogger("util", numPlayers, iterations/100)
+	src := chance.NewLive(*seed)
+	utilLogger := NewAvgLogger("util", numPlayers, *iterations/100)
 	utilLogger.Precision = 6
-	for i := 0; i < iterations; i++ {
-		dudo := NewDudo(diceFaces, numDices)
-		util := cfrpar(dudo, probs, nodeMaps, stacks)
+	for i := 0; i < *iterations; i++ {
+		game := dudo.NewDudo(diceFaces, numDices)
+		util := cfrpar(game, probs, nodeMaps, stacks, src)
 
 		utilLogger.Add(util)
 	}
@@ -705,5 +574,11 @@ func main() {
 			nodeMap[k] = v
 		}
 	}
-	printNodeMap(nodeMap, numPlayers)
+	printNodeMap(game, nodeMap)
+
+	if *savePath != "" {
+		if err := saveNodeMap(*savePath, diceFaces, numDices, nodeMap); err != nil {
+			glog.Fatalf("%+v", err)
+		}
+	}
 }
@@ -0,0 +1,128 @@
+package strategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// infoset is an infoset of the strategies of a File.
+type infoset struct {
+	Name    string    `json:"name"`
+	Player  int       `json:"player"`
+	Actions []string  `json:"actions"`
+	Probs   []float64 `json:"probs"`
+}
+
+// Handler serves the strategy of a File as JSON.
+// The infosets are those of File.Strategies, named by FormatInfoset, such as "2pb" in Kuhn and "35|1x2,2x6" in Dudo.
+//
+//	GET /infoset?name=2pb     returns the actions and their probabilities
+//	GET /sample?name=2pb      returns an action sampled from the strategy
+//	GET /infosets?player=0    returns the names of the infosets of player
+type Handler struct {
+	mux      *http.ServeMux
+	infosets map[string]*infoset
+	// playerInfosets are the sorted infoset names of each player.
+	playerInfosets [][]string
+}
+
+func NewHandler(f *File) (*Handler, error) {
+	root, err := f.NewGame()
+	if err != nil {
+		return nil, errors.Wrap(err, "NewGame")
+	}
+	h := &Handler{
+		mux:            http.NewServeMux(),
+		infosets:       make(map[string]*infoset),
+		playerInfosets: make([][]string, root.NumPlayers()),
+	}
+	for key, probs := range f.Strategies {
+		player, state, err := infosetState(root, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "infoset %q", key)
+		}
+		if player < 0 || player >= len(h.playerInfosets) {
+			return nil, errors.Errorf("infoset %q of player %d", key, player)
+		}
+		actions := ActionNames(state)
+		if len(probs) != len(actions) {
+			return nil, errors.Errorf("infoset %q has %d probabilities for %d actions", key, len(probs), len(actions))
+		}
+		name := FormatInfoset(root, key)
+		h.infosets[name] = &infoset{Name: name, Player: player, Actions: actions, Probs: probs}
+		h.playerInfosets[player] = append(h.playerInfosets[player], name)
+	}
+	for _, infosets := range h.playerInfosets {
+		sort.Strings(infosets)
+	}
+
+	h.mux.HandleFunc("/infoset", h.handleInfoset)
+	h.mux.HandleFunc("/sample", h.handleSample)
+	h.mux.HandleFunc("/infosets", h.handleInfosets)
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) getInfoset(w http.ResponseWriter, r *http.Request) (*infoset, bool) {
+	name := r.URL.Query().Get("name")
+	is, ok := h.infosets[name]
+	if !ok {
+		http.Error(w, "unknown infoset "+strconv.Quote(name), http.StatusNotFound)
+		return nil, false
+	}
+	return is, true
+}
+
+func (h *Handler) handleInfoset(w http.ResponseWriter, r *http.Request) {
+	is, ok := h.getInfoset(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, is)
+}
+
+func (h *Handler) handleSample(w http.ResponseWriter, r *http.Request) {
+	is, ok := h.getInfoset(w, r)
+	if !ok {
+		return
+	}
+	resp := struct {
+		Name   string `json:"name"`
+		Action string `json:"action"`
+	}{
+		Name:   is.Name,
+		Action: is.Actions[SampleAction(is.Probs)],
+	}
+	writeJSON(w, resp)
+}
+
+func (h *Handler) handleInfosets(w http.ResponseWriter, r *http.Request) {
+	player, err := strconv.Atoi(r.URL.Query().Get("player"))
+	if err != nil || player < 0 || player >= len(h.playerInfosets) {
+		http.Error(w, "invalid player "+strconv.Quote(r.URL.Query().Get("player")), http.StatusBadRequest)
+		return
+	}
+	resp := struct {
+		Player   int      `json:"player"`
+		Infosets []string `json:"infosets"`
+	}{
+		Player:   player,
+		Infosets: h.playerInfosets[player],
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		glog.Errorf("%+v", errors.Wrap(err, "Encode"))
+	}
+}
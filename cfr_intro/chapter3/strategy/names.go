@@ -0,0 +1,126 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+// ActionNames returns the human readable actions of a game state,
+// such as "pass" and "bet" in Kuhn, or claims such as "3x5" and "dudo" in Dudo.
+func ActionNames(game tree.Game) []string {
+	namer, ok := game.(interface{ ActionName(aIdx int) string })
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, game.ActionsLen())
+	for aIdx := 0; aIdx < game.ActionsLen(); aIdx++ {
+		names = append(names, namer.ActionName(aIdx))
+	}
+	return names
+}
+
+// Private returns the private information of player, which is her card or her dices, or her side of a matrix game.
+func Private(game tree.Game, player int) string {
+	switch g := game.(type) {
+	case games.Kuhn:
+		return fmt.Sprintf("card %d", g.State.Card(player))
+	case games.Leduc:
+		s := fmt.Sprintf("card %s", leduc.CardString(g.State.PrivateCard(player)))
+		if g.State.PublicCard() >= 0 {
+			s += fmt.Sprintf(", public card %s", leduc.CardString(g.State.PublicCard()))
+		}
+		return s
+	case games.Dudo:
+		dices := make([]string, 0)
+		for _, d := range g.State.Dices(player) {
+			dices = append(dices, strconv.Itoa(int(d)))
+		}
+		return fmt.Sprintf("dices %s", strings.Join(dices, " "))
+	case games.Matrix:
+		if player == 0 {
+			return "the rows"
+		}
+		return "the columns"
+	}
+	return ""
+}
+
+// InfosetName returns the human readable name of the infoset of game,
+// which is the infoset itself for Kuhn, and dudo.Dudo.FormatInfoset for Dudo.
+func InfosetName(game tree.Game) string {
+	return FormatInfoset(game, game.Infoset())
+}
+
+// FormatInfoset returns the human readable name of an infoset of the game of root, as InfosetName.
+func FormatInfoset(root tree.Game, infoset string) string {
+	if g, ok := root.(games.Dudo); ok {
+		return g.State.FormatInfoset(infoset)
+	}
+	return infoset
+}
+
+// infosetState returns the player of an infoset of the game of root, and a state with the actions of the infoset,
+// by replaying the actions written in the infoset from root.
+// The state deals no cards or dices, so only its player and its actions are meaningful.
+func infosetState(root tree.Game, infoset string) (int, tree.Game, error) {
+	switch g := root.(type) {
+	case games.Kuhn:
+		// Skip the card, such as "2" in "2pb".
+		history := strings.TrimLeft(infoset, "0123456789")
+		if len(history) == len(infoset) {
+			return -1, nil, errors.Errorf("no card")
+		}
+		state := g.State.Clone()
+		for _, c := range []byte(history) {
+			a := strings.IndexByte("pbr", c)
+			if a < 0 {
+				return -1, nil, errors.Errorf("unknown action %q", c)
+			}
+			state = state.Play(uint8(a))
+		}
+		return state.CurPlayer(), games.Kuhn{State: state}, nil
+	case games.Leduc:
+		// Skip the ranks, such as "KJ" in "KJ:rc/r".
+		colon := strings.IndexByte(infoset, ':')
+		if colon < 0 {
+			return -1, nil, errors.Errorf("no ':'")
+		}
+		state := g.State.Clone()
+		for _, c := range []byte(strings.Replace(infoset[colon+1:], "/", "", -1)) {
+			a := strings.IndexByte("fcr", c)
+			if a < 0 {
+				return -1, nil, errors.Errorf("unknown action %q", c)
+			}
+			state = state.Play(uint8(a))
+		}
+		return state.CurPlayer(), games.Leduc{State: state}, nil
+	case games.Dudo:
+		// With imperfect recall, the remembered claims still end with the last claim,
+		// which with the first in palifico rounds is all that the actions depend on.
+		_, player, claims, err := g.State.ParseInfoset(infoset)
+		if err != nil {
+			return -1, nil, errors.Wrap(err, "ParseInfoset")
+		}
+		state := g.State.Clone()
+		for _, c := range claims {
+			state = state.Play(c)
+		}
+		return player, games.Dudo{State: state}, nil
+	case games.Matrix:
+		if len(infoset) != 1 || (infoset[0] != '0' && infoset[0] != '1') {
+			return -1, nil, errors.Errorf("unknown player")
+		}
+		var state tree.Game = g
+		if infoset[0] == '1' {
+			state = g.Play(0)
+		}
+		return state.CurPlayer(), state, nil
+	}
+	return -1, nil, errors.Errorf("unknown game %T", root)
+}
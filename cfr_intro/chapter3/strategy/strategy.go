@@ -0,0 +1,142 @@
+// Package strategy saves and loads trained strategies together with the rules of their game,
+// so that they can be played against after training.
+package strategy
+
+import (
+	"encoding/gob"
+	"math/rand"
+	"os"
+	"reflect"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/leduc"
+	"github.com/fumin/bangbang/cfr/chapter3/matrix"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/pkg/errors"
+)
+
+const (
+	Kuhn   = "kuhn"
+	Leduc  = "leduc"
+	Dudo   = "dudo"
+	Matrix = "matrix"
+)
+
+// File is a strategy and the game it is trained on.
+// Files are gob encoded, since infosets such as those of dudo.Dudo are not valid UTF-8.
+type File struct {
+	// Game is Kuhn, Leduc, Dudo or Matrix.
+	Game   string
+	Kuhn   kuhn.Config
+	Matrix matrix.Config
+
+	DudoRules dudo.Rules
+	NumDices  []uint8
+	Recall    int
+
+	// Strategies are the average strategies keyed by the infosets of Game.
+	Strategies map[string][]float64
+}
+
+func Save(path string, f *File) error {
+	w, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "os.Create")
+	}
+	defer w.Close()
+	if err := gob.NewEncoder(w).Encode(f); err != nil {
+		return errors.Wrap(err, "Encode")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "Close")
+	}
+	return nil
+}
+
+func Load(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.Open")
+	}
+	defer r.Close()
+	f := &File{}
+	if err := gob.NewDecoder(r).Decode(f); err != nil {
+		return nil, errors.Wrap(err, "Decode")
+	}
+	return f, nil
+}
+
+// NewGame returns the root of the game of f.
+func (f *File) NewGame() (tree.Game, error) {
+	switch f.Game {
+	case Kuhn:
+		game, err := kuhn.NewKuhnConfig(f.Kuhn)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewKuhnConfig")
+		}
+		return games.Kuhn{State: game}, nil
+	case Leduc:
+		return games.Leduc{State: leduc.NewLeduc()}, nil
+	case Dudo:
+		game, err := dudo.NewDudoRules(f.DudoRules, f.NumDices)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewDudoRules")
+		}
+		game.Recall = f.Recall
+		return games.Dudo{State: game}, nil
+	case Matrix:
+		game, err := matrix.NewMatrix(f.Matrix)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewMatrix")
+		}
+		return games.Matrix{State: game}, nil
+	}
+	return nil, errors.Errorf("unknown game %q", f.Game)
+}
+
+// SameGame reports whether f and g are strategies of the same game with the same rules.
+func (f *File) SameGame(g *File) bool {
+	fg, gg := *f, *g
+	fg.Strategies, gg.Strategies = nil, nil
+	return reflect.DeepEqual(fg, gg)
+}
+
+// Strategy returns the strategy of an infoset with numActions actions,
+// which is uniformly random if the infoset is never trained.
+func (f *File) Strategy(infoset string, numActions int) []float64 {
+	strategy, ok := f.Strategies[infoset]
+	if ok && len(strategy) == numActions {
+		return strategy
+	}
+	strategy = make([]float64, numActions)
+	for i := range strategy {
+		strategy[i] = 1 / float64(numActions)
+	}
+	return strategy
+}
+
+// Policy returns the probabilities of the actions at a decision state.
+// Although a Policy is given the whole state, it should only depend on the infoset of the current player.
+type Policy interface {
+	Probs(game tree.Game) []float64
+}
+
+// Probs returns the strategy of the infoset of game, so that a File is a Policy.
+func (f *File) Probs(game tree.Game) []float64 {
+	return f.Strategy(game.Infoset(), game.ActionsLen())
+}
+
+// SampleAction returns an action index sampled from strategy.
+func SampleAction(strategy []float64) int {
+	r := rand.Float64()
+	var cumulative float64 = 0
+	for a := 0; a < len(strategy)-1; a++ {
+		cumulative += strategy[a]
+		if r < cumulative {
+			return a
+		}
+	}
+	return len(strategy) - 1
+}
@@ -0,0 +1,102 @@
+package tree
+
+import (
+	"strconv"
+)
+
+// Action is the type of the actions of a game, such as uint8 for kuhn.Kuhn and uint16 for dudo.Dudo.
+type Action interface {
+	uint8 | uint16
+}
+
+// State is the state of a game such as kuhn.Kuhn, whose Chance deals in place,
+// and whose Play returns the next state, which may share memory with the current one.
+type State[S any, A Action] interface {
+	NumPlayers() int
+	IsTerminal() bool
+	Payoff(outPayoff []float64)
+	IsChanceNode() bool
+	ChanceLen() int
+	ChanceProb(outcome int) float64
+	Chance(outcome int)
+	CurPlayer() int
+	ActionsLen() int
+	Actions(outActions []A)
+	Play(a A) S
+	InfosetLen() int
+	Infoset(outInfoset []uint8)
+	// Clone returns a copy that shares no memory with the state.
+	Clone() S
+}
+
+// Adapter adapts a State to Game, cloning the state before every move so that no two states share memory.
+// Package games names the adapters of the games of this chapter, such as games.Kuhn.
+type Adapter[S State[S, A], A Action] struct {
+	State S
+}
+
+func (g Adapter[S, A]) NumPlayers() int {
+	return g.State.NumPlayers()
+}
+
+func (g Adapter[S, A]) IsTerminal() bool {
+	return g.State.IsTerminal()
+}
+
+func (g Adapter[S, A]) Payoff(outPayoff []float64) {
+	g.State.Payoff(outPayoff)
+}
+
+func (g Adapter[S, A]) IsChanceNode() bool {
+	return g.State.IsChanceNode()
+}
+
+func (g Adapter[S, A]) ChanceLen() int {
+	return g.State.ChanceLen()
+}
+
+func (g Adapter[S, A]) ChanceProb(outcome int) float64 {
+	return g.State.ChanceProb(outcome)
+}
+
+func (g Adapter[S, A]) Chance(outcome int) Game {
+	child := g.State.Clone()
+	child.Chance(outcome)
+	return Adapter[S, A]{child}
+}
+
+func (g Adapter[S, A]) CurPlayer() int {
+	return g.State.CurPlayer()
+}
+
+func (g Adapter[S, A]) ActionsLen() int {
+	return g.State.ActionsLen()
+}
+
+// Actions writes the actions of the state, in the order of their indices.
+func (g Adapter[S, A]) Actions(outActions []A) {
+	g.State.Actions(outActions)
+}
+
+func (g Adapter[S, A]) Play(aIdx int) Game {
+	actions := make([]A, g.ActionsLen())
+	g.State.Actions(actions)
+	return Adapter[S, A]{g.State.Clone().Play(actions[aIdx])}
+}
+
+func (g Adapter[S, A]) Infoset() string {
+	buf := make([]uint8, g.State.InfosetLen())
+	g.State.Infoset(buf)
+	return string(buf)
+}
+
+// ActionName returns the name of the action of index aIdx, given by the ActionString of the state if any,
+// such as "bet" in Kuhn, and the action itself otherwise, such as a bid in Oshi-Zumo.
+func (g Adapter[S, A]) ActionName(aIdx int) string {
+	actions := make([]A, g.ActionsLen())
+	g.State.Actions(actions)
+	if namer, ok := any(g.State).(interface{ ActionString(a A) string }); ok {
+		return namer.ActionString(actions[aIdx])
+	}
+	return strconv.Itoa(int(actions[aIdx]))
+}
@@ -0,0 +1,325 @@
+// Package tree precomputes the full tree of a small game into flat arrays,
+// and runs CFR over it as forward and backward sweeps instead of recursion.
+//
+// The idea is the same as the fixed-strategy iteration CFR (FSICFR) in
+// http://modelai.gettysburg.edu/2013/cfr/cfr.pdf
+package tree
+
+import (
+	"fmt"
+)
+
+const (
+	// Player of chance nodes.
+	Chance = -1
+	// Player of terminal nodes.
+	Terminal = -2
+)
+
+// Game is a game state that can be expanded into a Tree.
+// Chance and Play must return states that share nothing with the receiver.
+type Game interface {
+	NumPlayers() int
+	IsTerminal() bool
+	Payoff(outPayoff []float64)
+
+	IsChanceNode() bool
+	// ChanceLen returns the number of chance outcomes.
+	ChanceLen() int
+	ChanceProb(outcome int) float64
+	Chance(outcome int) Game
+
+	CurPlayer() int
+	ActionsLen() int
+	Play(aIdx int) Game
+	Infoset() string
+}
+
+// Tree is a game tree stored as arrays indexed by node id.
+// Nodes are in breadth first order, so a parent always precedes its children,
+// and the children of a node have consecutive ids.
+type Tree struct {
+	NumPlayers int
+
+	Parent      []int32
+	FirstChild  []int32
+	NumChildren []int32
+	// Player is the player to act, or Chance, or Terminal.
+	Player []int8
+	// Infoset is the infoset id of decision nodes, and -1 for other nodes.
+	Infoset []int32
+	// ChanceProb is the probability that chance picks this node at its parent,
+	// or 1 if the parent is not a chance node.
+	ChanceProb []float64
+	// Payoff holds NumPlayers payoffs for each node, which are zeros for non terminal nodes.
+	Payoff []float64
+
+	// Infoset arrays, indexed by infoset id.
+	InfosetKey    []string
+	InfosetPlayer []int8
+	// The actions of infoset i are InfosetOffset[i] to InfosetOffset[i+1]
+	// in the per-action arrays such as CFR.RegretSum.
+	InfosetOffset []int32
+}
+
+func (t *Tree) NumNodes() int {
+	return len(t.Parent)
+}
+
+func (t *Tree) NumInfosets() int {
+	return len(t.InfosetKey)
+}
+
+func (t *Tree) NumActions(infoset int) int {
+	return int(t.InfosetOffset[infoset+1] - t.InfosetOffset[infoset])
+}
+
+func (t *Tree) addNode(parent int32, chanceProb float64) int32 {
+	id := int32(len(t.Parent))
+	t.Parent = append(t.Parent, parent)
+	t.FirstChild = append(t.FirstChild, -1)
+	t.NumChildren = append(t.NumChildren, 0)
+	t.Player = append(t.Player, Terminal)
+	t.Infoset = append(t.Infoset, -1)
+	t.ChanceProb = append(t.ChanceProb, chanceProb)
+	for p := 0; p < t.NumPlayers; p++ {
+		t.Payoff = append(t.Payoff, 0)
+	}
+	return id
+}
+
+// Build expands all the states reachable from root.
+func Build(root Game) *Tree {
+	t := &Tree{NumPlayers: root.NumPlayers()}
+	t.InfosetOffset = []int32{0}
+	infosetIDs := make(map[string]int32)
+
+	t.addNode(-1, 1)
+	queue := []Game{root}
+	for id := int32(0); int(id) < len(queue); id++ {
+		game := queue[id]
+		// Release the state once we are done with it.
+		queue[id] = nil
+
+		if game.IsTerminal() {
+			t.Player[id] = Terminal
+			game.Payoff(t.Payoff[int(id)*t.NumPlayers : int(id+1)*t.NumPlayers])
+			continue
+		}
+
+		if game.IsChanceNode() {
+			t.Player[id] = Chance
+			numOutcomes := game.ChanceLen()
+			t.FirstChild[id] = int32(len(t.Parent))
+			t.NumChildren[id] = int32(numOutcomes)
+			for o := 0; o < numOutcomes; o++ {
+				t.addNode(id, game.ChanceProb(o))
+				queue = append(queue, game.Chance(o))
+			}
+			continue
+		}
+
+		player := game.CurPlayer()
+		numActions := game.ActionsLen()
+		key := game.Infoset()
+		infoset, ok := infosetIDs[key]
+		if !ok {
+			infoset = int32(len(t.InfosetKey))
+			infosetIDs[key] = infoset
+			t.InfosetKey = append(t.InfosetKey, key)
+			t.InfosetPlayer = append(t.InfosetPlayer, int8(player))
+			t.InfosetOffset = append(t.InfosetOffset, t.InfosetOffset[infoset]+int32(numActions))
+		}
+		if t.NumActions(int(infoset)) != numActions {
+			panic(fmt.Sprintf("infoset %q has %d actions, but node %d has %d", key, t.NumActions(int(infoset)), id, numActions))
+		}
+
+		t.Player[id] = int8(player)
+		t.Infoset[id] = infoset
+		t.FirstChild[id] = int32(len(t.Parent))
+		t.NumChildren[id] = int32(numActions)
+		for a := 0; a < numActions; a++ {
+			t.addNode(id, 1)
+			queue = append(queue, game.Play(a))
+		}
+	}
+
+	return t
+}
+
+// CFR runs vanilla CFR over a Tree without recursion or allocation.
+type CFR struct {
+	Tree        *Tree
+	RegretSum   []float64
+	StrategySum []float64
+
+	strategy []float64
+	// reach holds NumPlayers+1 reach probabilities for each node, the last one being chance's.
+	reach []float64
+	// util holds NumPlayers utilities for each node.
+	util []float64
+}
+
+func NewCFR(t *Tree) *CFR {
+	numActions := t.InfosetOffset[t.NumInfosets()]
+	c := &CFR{
+		Tree:        t,
+		RegretSum:   make([]float64, numActions),
+		StrategySum: make([]float64, numActions),
+		strategy:    make([]float64, numActions),
+		reach:       make([]float64, t.NumNodes()*(t.NumPlayers+1)),
+		util:        make([]float64, t.NumNodes()*t.NumPlayers),
+	}
+	return c
+}
+
+// Iterate runs one CFR iteration and returns the utilities of the root.
+// The returned slice is only valid until the next iteration.
+func (c *CFR) Iterate() []float64 {
+	t := c.Tree
+	numPlayers := t.NumPlayers
+	stride := numPlayers + 1
+
+	// Compute the current strategy of every infoset by regret matching.
+	for is := 0; is < t.NumInfosets(); is++ {
+		lo, hi := t.InfosetOffset[is], t.InfosetOffset[is+1]
+		regretMatch(c.RegretSum[lo:hi], c.strategy[lo:hi])
+	}
+
+	// Forward sweep: push reach probabilities from parents to children.
+	for p := 0; p < stride; p++ {
+		c.reach[p] = 1
+	}
+	for n := 0; n < t.NumNodes(); n++ {
+		player := int(t.Player[n])
+		if player == Terminal {
+			continue
+		}
+		reach := c.reach[n*stride : (n+1)*stride]
+		first := int(t.FirstChild[n])
+		for i := 0; i < int(t.NumChildren[n]); i++ {
+			child := first + i
+			childReach := c.reach[child*stride : (child+1)*stride]
+			copy(childReach, reach)
+			if player == Chance {
+				childReach[numPlayers] *= t.ChanceProb[child]
+			} else {
+				childReach[player] *= c.strategy[int(t.InfosetOffset[t.Infoset[n]])+i]
+			}
+		}
+	}
+
+	// Backward sweep: pull utilities from children to parents, and accumulate regrets.
+	for n := t.NumNodes() - 1; n >= 0; n-- {
+		util := c.util[n*numPlayers : (n+1)*numPlayers]
+		player := int(t.Player[n])
+		if player == Terminal {
+			copy(util, t.Payoff[n*numPlayers:(n+1)*numPlayers])
+			continue
+		}
+
+		for p := range util {
+			util[p] = 0
+		}
+		first := int(t.FirstChild[n])
+		numChildren := int(t.NumChildren[n])
+		if player == Chance {
+			for i := 0; i < numChildren; i++ {
+				child := first + i
+				prob := t.ChanceProb[child]
+				for p := range util {
+					util[p] += prob * c.util[child*numPlayers+p]
+				}
+			}
+			continue
+		}
+
+		offset := int(t.InfosetOffset[t.Infoset[n]])
+		for i := 0; i < numChildren; i++ {
+			child := first + i
+			actProb := c.strategy[offset+i]
+			for p := range util {
+				util[p] += actProb * c.util[child*numPlayers+p]
+			}
+		}
+
+		// Calculate the counterfactual probability.
+		reach := c.reach[n*stride : (n+1)*stride]
+		var probNegI float64 = 1
+		for p, prb := range reach {
+			if p == player {
+				continue
+			}
+			probNegI *= prb
+		}
+		// Update the regrets.
+		probI := reach[player]
+		for i := 0; i < numChildren; i++ {
+			child := first + i
+			regret := c.util[child*numPlayers+player] - util[player]
+			c.RegretSum[offset+i] += probNegI * regret
+			c.StrategySum[offset+i] += probI * c.strategy[offset+i]
+		}
+	}
+
+	return c.util[0:numPlayers]
+}
+
+// AvgStrategy returns the average strategy of an infoset.
+func (c *CFR) AvgStrategy(infoset int) []float64 {
+	lo, hi := c.Tree.InfosetOffset[infoset], c.Tree.InfosetOffset[infoset+1]
+	strategySum := c.StrategySum[lo:hi]
+
+	var z float64 = 0
+	for _, s := range strategySum {
+		z += s
+	}
+
+	avgStrat := make([]float64, len(strategySum))
+	if z == 0 {
+		for i := range avgStrat {
+			avgStrat[i] = 1 / float64(len(avgStrat))
+		}
+		return avgStrat
+	}
+
+	for i, s := range strategySum {
+		avgStrat[i] = s / z
+	}
+	return avgStrat
+}
+
+// AvgStrategies returns the average strategy of all infosets, keyed by Game.Infoset.
+func (c *CFR) AvgStrategies() map[string][]float64 {
+	strategies := make(map[string][]float64)
+	for is, key := range c.Tree.InfosetKey {
+		strategies[key] = c.AvgStrategy(is)
+	}
+	return strategies
+}
+
+func regretMatch(regretSum, outStrategy []float64) {
+	var z float64 = 0
+	for _, r := range regretSum {
+		if r < 0 {
+			continue
+		}
+		z += r
+	}
+
+	if z == 0 {
+		numActions := len(outStrategy)
+		for i := 0; i < numActions; i++ {
+			outStrategy[i] = float64(1) / float64(numActions)
+		}
+		return
+	}
+
+	for i, r := range regretSum {
+		if r < 0 {
+			outStrategy[i] = 0
+		} else {
+			outStrategy[i] = r / z
+		}
+	}
+}
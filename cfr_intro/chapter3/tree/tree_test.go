@@ -0,0 +1,29 @@
+package tree_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumin/bangbang/cfr/chapter3/exploit"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/kuhn"
+	"github.com/fumin/bangbang/cfr/chapter3/match"
+	"github.com/fumin/bangbang/cfr/chapter3/strategy"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+)
+
+func TestCFRKuhn(t *testing.T) {
+	root := games.Kuhn{State: kuhn.NewKuhn()}
+	c := tree.NewCFR(tree.Build(root))
+	for i := 0; i < 300000; i++ {
+		c.Iterate()
+	}
+	avg := &strategy.File{Strategies: c.AvgStrategies()}
+
+	if v := match.Value(root, avg)[0]; math.Abs(v-kuhn.Value) > 1e-4 {
+		t.Errorf("value %f, expected %f", v, kuhn.Value)
+	}
+	if e := exploit.Exploitability(root, avg); e > 1e-3 {
+		t.Errorf("exploitability %f, expected less than 1e-3", e)
+	}
+}
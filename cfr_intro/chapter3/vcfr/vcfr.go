@@ -0,0 +1,260 @@
+// Package vcfr implements vectorised CFR for two player Dudo.
+//
+// Instead of sampling dices and traversing one private deal at a time,
+// each iteration traverses the public claim tree once,
+// carrying for each player a vector of reach probabilities over all her private rolls.
+// Terminal utilities are then matrix-vector products over pairs of rolls.
+package vcfr
+
+import (
+	"fmt"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+)
+
+type node struct {
+	history    []uint16
+	numActions int
+	// regretSum and strategySum are indexed by roll*numActions + action.
+	regretSum   []float64
+	strategySum []float64
+}
+
+func newNode(history []uint16, numRolls, numActions int) *node {
+	nd := &node{
+		history:     history,
+		numActions:  numActions,
+		regretSum:   make([]float64, numRolls*numActions),
+		strategySum: make([]float64, numRolls*numActions),
+	}
+	return nd
+}
+
+func (nd *node) getStrategy(outStrategy []float64) {
+	for roll := 0; roll < len(nd.regretSum)/nd.numActions; roll++ {
+		lo, hi := roll*nd.numActions, (roll+1)*nd.numActions
+		regret := nd.regretSum[lo:hi]
+		strategy := outStrategy[lo:hi]
+
+		var z float64 = 0
+		for _, r := range regret {
+			if r < 0 {
+				continue
+			}
+			z += r
+		}
+		for i, r := range regret {
+			if z == 0 {
+				strategy[i] = 1 / float64(nd.numActions)
+			} else if r < 0 {
+				strategy[i] = 0
+			} else {
+				strategy[i] = r / z
+			}
+		}
+	}
+}
+
+// payoffCache caches the payoffs of terminal states over all pairs of rolls.
+type payoffCache struct {
+	numRolls [2]int
+	// rollProb are the probabilities of each player's rolls.
+	rollProb [2][]float64
+	// payoffs are keyed by the player of the challenged claim, the claim and the terminal action.
+	// They are indexed by (roll0*numRolls[1] + roll1)*2 + player.
+	payoffs map[[3]int][]float64
+}
+
+func newPayoffCache(game dudo.Dudo) *payoffCache {
+	if game.NumPlayers() != 2 {
+		panic(fmt.Sprintf("vectorised CFR needs 2 players, got %d", game.NumPlayers()))
+	}
+
+	pc := &payoffCache{
+		payoffs: make(map[[3]int][]float64),
+	}
+	for p := range pc.numRolls {
+		pc.numRolls[p] = game.RollLen(p)
+		pc.rollProb[p] = make([]float64, pc.numRolls[p])
+		for i := range pc.rollProb[p] {
+			pc.rollProb[p][i] = game.RollProb(p, i)
+		}
+	}
+	return pc
+}
+
+func (pc *payoffCache) get(game dudo.Dudo) []float64 {
+	history := game.History()
+	claimIdx := len(history) - 2
+	key := [3]int{claimIdx % 2, int(history[claimIdx]), int(history[claimIdx+1])}
+	payoffs, ok := pc.payoffs[key]
+	if ok {
+		return payoffs
+	}
+
+	payoffs = make([]float64, pc.numRolls[0]*pc.numRolls[1]*2)
+	for i := 0; i < pc.numRolls[0]; i++ {
+		game.Roll(0, i)
+		for j := 0; j < pc.numRolls[1]; j++ {
+			game.Roll(1, j)
+			pairIdx := i*pc.numRolls[1] + j
+			game.Payoff(payoffs[pairIdx*2 : (pairIdx+1)*2])
+		}
+	}
+	pc.payoffs[key] = payoffs
+	return payoffs
+}
+
+type VCFR struct {
+	*payoffCache
+	root dudo.Dudo
+
+	// nodes are keyed by the public claim history.
+	nodes map[string]*node
+}
+
+func New(game dudo.Dudo) *VCFR {
+	v := &VCFR{
+		payoffCache: newPayoffCache(game),
+		root:        game.Clone(),
+		nodes:       make(map[string]*node),
+	}
+	// Nodes are keyed by the full public history, so there is no abstraction.
+	v.root.Recall = 0
+	return v
+}
+
+// Iterate runs one CFR iteration and returns the expected utility of player 0.
+func (v *VCFR) Iterate() float64 {
+	var reach [2][]float64
+	for p := range reach {
+		reach[p] = make([]float64, v.numRolls[p])
+		for i := range reach[p] {
+			reach[p][i] = 1
+		}
+	}
+
+	val := v.cfr(v.root.Clone(), reach)
+
+	var util float64 = 0
+	for _, u := range val[0] {
+		util += u
+	}
+	return util
+}
+
+// cfr returns the counterfactual values of each player's rolls.
+func (v *VCFR) cfr(game dudo.Dudo, reach [2][]float64) [2][]float64 {
+	var val [2][]float64
+	for p := range val {
+		val[p] = make([]float64, v.numRolls[p])
+	}
+
+	if game.IsTerminal() {
+		payoffs := v.payoffCache.get(game)
+		for i := 0; i < v.numRolls[0]; i++ {
+			for j := 0; j < v.numRolls[1]; j++ {
+				pairIdx := i*v.numRolls[1] + j
+				chanceProb := v.rollProb[0][i] * v.rollProb[1][j]
+				val[0][i] += chanceProb * payoffs[pairIdx*2] * reach[1][j]
+				val[1][j] += chanceProb * payoffs[pairIdx*2+1] * reach[0][i]
+			}
+		}
+		return val
+	}
+
+	player := game.CurPlayer()
+	opponent := 1 - player
+	numRolls := v.numRolls[player]
+
+	actions := make([]uint16, game.ActionsLen())
+	game.Actions(actions)
+	numActions := len(actions)
+	key := historyKey(game.History())
+	nd, ok := v.nodes[key]
+	if !ok {
+		history := make([]uint16, len(game.History()))
+		copy(history, game.History())
+		nd = newNode(history, numRolls, numActions)
+		v.nodes[key] = nd
+	}
+	strategy := make([]float64, numRolls*numActions)
+	nd.getStrategy(strategy)
+
+	actionVal := make([]float64, numRolls*numActions)
+	for aIdx, a := range actions {
+		// Only the reach of the current player changes in the subtree.
+		var stReach [2][]float64
+		stReach[opponent] = reach[opponent]
+		stReach[player] = make([]float64, numRolls)
+		for i := range stReach[player] {
+			stReach[player][i] = reach[player][i] * strategy[i*numActions+aIdx]
+		}
+
+		stVal := v.cfr(game.Play(a), stReach)
+
+		for i, u := range stVal[player] {
+			actionVal[i*numActions+aIdx] = u
+			val[player][i] += strategy[i*numActions+aIdx] * u
+		}
+		for j, u := range stVal[opponent] {
+			val[opponent][j] += u
+		}
+	}
+
+	// Update the regrets. The counterfactual values are already weighted by
+	// the probabilities of chance and the opponent.
+	for i := 0; i < numRolls; i++ {
+		for aIdx := 0; aIdx < numActions; aIdx++ {
+			idx := i*numActions + aIdx
+			nd.regretSum[idx] += actionVal[idx] - val[player][i]
+			nd.strategySum[idx] += reach[player][i] * strategy[idx]
+		}
+	}
+
+	return val
+}
+
+// historyKey encodes a history of actions into a map key, two bytes per action.
+func historyKey(history []uint16) string {
+	key := make([]byte, 2*len(history))
+	for i, a := range history {
+		key[2*i] = byte(a >> 8)
+		key[2*i+1] = byte(a)
+	}
+	return string(key)
+}
+
+// AvgStrategies returns the average strategy of all infosets, keyed by dudo.Dudo.Infoset.
+func (v *VCFR) AvgStrategies() map[string][]float64 {
+	strategies := make(map[string][]float64)
+	for _, nd := range v.nodes {
+		game := v.root.Clone()
+		for _, a := range nd.history {
+			game = game.Play(a)
+		}
+		player := game.CurPlayer()
+
+		for roll := 0; roll < v.numRolls[player]; roll++ {
+			game.Roll(player, roll)
+			infoset := make([]uint8, game.InfosetLen())
+			game.Infoset(infoset)
+
+			strategySum := nd.strategySum[roll*nd.numActions : (roll+1)*nd.numActions]
+			var z float64 = 0
+			for _, s := range strategySum {
+				z += s
+			}
+			avgStrat := make([]float64, nd.numActions)
+			for i, s := range strategySum {
+				if z == 0 {
+					avgStrat[i] = 1 / float64(nd.numActions)
+				} else {
+					avgStrat[i] = s / z
+				}
+			}
+			strategies[string(infoset)] = avgStrat
+		}
+	}
+	return strategies
+}
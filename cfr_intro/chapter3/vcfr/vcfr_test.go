@@ -0,0 +1,39 @@
+package vcfr_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumin/bangbang/cfr/chapter3/dudo"
+	"github.com/fumin/bangbang/cfr/chapter3/games"
+	"github.com/fumin/bangbang/cfr/chapter3/tree"
+	"github.com/fumin/bangbang/cfr/chapter3/vcfr"
+)
+
+// TestMatchesTree checks that vectorised CFR updates the same strategies as CFR over the full tree in 1v1 Dudo,
+// as both are vanilla CFR and only differ in how they traverse the game.
+func TestMatchesTree(t *testing.T) {
+	game := dudo.NewDudo(3, []uint8{1, 1})
+	c := tree.NewCFR(tree.Build(games.Dudo{State: game}))
+	v := vcfr.New(game)
+	for i := 0; i < 1000; i++ {
+		c.Iterate()
+		v.Iterate()
+	}
+
+	expected, strategies := c.AvgStrategies(), v.AvgStrategies()
+	if len(strategies) != len(expected) {
+		t.Fatalf("%d infosets, expected %d", len(strategies), len(expected))
+	}
+	for infoset, e := range expected {
+		s, ok := strategies[infoset]
+		if !ok {
+			t.Fatalf("missing infoset %s", game.FormatInfoset(infoset))
+		}
+		for a := range e {
+			if math.Abs(s[a]-e[a]) > 1e-9 {
+				t.Fatalf("infoset %s: %v, expected %v", game.FormatInfoset(infoset), s, e)
+			}
+		}
+	}
+}